@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/kentik/custom-notification-templates/pkg/render"
+)
+
+type fileIssue struct {
+	File string `json:"file"`
+	render.LintIssue
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "Print issues as JSON")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatalf("Usage: %s [-json] template...", os.Args[0])
+	}
+
+	issues := []fileIssue{}
+	failed := false
+	for _, path := range flag.Args() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Error reading %s: %s", path, err)
+		}
+		for _, issue := range render.Lint(string(content)) {
+			issues = append(issues, fileIssue{File: path, LintIssue: issue})
+			if issue.Severity == render.Severity_Error {
+				failed = true
+			}
+		}
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(issues, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding issues: %s", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, issue := range issues {
+			fmt.Printf("%s:%d:%d: %s: %s\n", issue.File, issue.StartLine, issue.StartColumn, issue.Severity, issue.Message)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/kentik/custom-notification-templates/pkg/render"
+	"github.com/kentik/custom-notification-templates/templates"
 )
 
 // resultErrWrapper formats an error as a JSON string for JS consumption.
@@ -18,53 +21,63 @@ func resultErrWrapper(err error) string {
 	return string(b)
 }
 
-// processRender handles the core rendering logic, independent of JS types.
-// It returns a JSON string containing the result or error.
-func processRender(templateText, dataJSON string) string {
-	// Capture panics within this function's scope (before goroutine)
-	defer func() {
-		if r := recover(); r != nil {
-			// This might be hard to return if we crashed, but we try.
-			// However, the goroutine handles most panics.
-		}
-	}()
+// renderTimeout and renderLimits bound a single render, templates are user-authored and untrusted.
+const renderTimeout = 5 * time.Second
+
+// yieldInterval is how often a render running under yieldingContext lets other goroutines run.
+const yieldInterval = 50 * time.Millisecond
+
+var renderLimits = render.RenderOptions{
+	MaxOutputBytes:     4 << 20,
+	MaxRangeIterations: 1_000_000,
+}
 
-	req := render.RenderRequest{
+// processRender handles the core rendering logic, independent of JS types,
+// escaping the output as JSON when jsonOutput is set.
+// It returns a JSON string containing the result or error.
+func processRender(templateText, dataJSON string, jsonOutput bool) string {
+	return processRenderRequest(render.RenderRequest{
 		Template: templateText,
 		Data:     json.RawMessage(dataJSON),
-	}
+		JSON:     jsonOutput,
+		Preview:  true,
+	})
+}
 
-	// Channel to receive the result or error/panic from the render goroutine
-	type renderResult struct {
-		resp render.RenderResponse
-		err  error
+// processRenderFixture renders the template against the named fixture from render.TestingViewModels.
+func processRenderFixture(templateText, fixture string) string {
+	data, ok := render.TestingViewModels[fixture]
+	if !ok {
+		return resultErrWrapper(fmt.Errorf("fixture %q not found", fixture))
 	}
-	ch := make(chan renderResult, 1)
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				ch <- renderResult{err: fmt.Errorf("panic in render: %v", r)}
-			}
-		}()
-		ch <- renderResult{resp: render.Render(req)}
-	}()
+	return processRenderRequest(render.RenderRequest{
+		Template: templateText,
+		Data:     data,
+		Preview:  true,
+	})
+}
 
-	select {
-	case res := <-ch:
-		if res.err != nil {
-			return resultErrWrapper(res.err)
-		}
+func processRenderRequest(req render.RenderRequest) string {
+	ctx, cancel := context.WithTimeout(context.Background(), renderTimeout)
+	defer cancel()
+	return processRenderContext(ctx, req)
+}
 
-		b, err := json.Marshal(res.resp)
-		if err != nil {
-			return resultErrWrapper(fmt.Errorf("Unexpected error: failed to marshal response: %v", err))
+// processRenderContext renders the request, stopping when ctx is done.
+func processRenderContext(ctx context.Context, req render.RenderRequest) (result string) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = resultErrWrapper(fmt.Errorf("panic in render: %v", r))
 		}
-		return string(b)
+	}()
+
+	resp := render.RenderContext(ctx, req, renderLimits)
 
-	case <-time.After(5 * time.Second):
-		return resultErrWrapper(fmt.Errorf("render timed out after 5s"))
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return resultErrWrapper(fmt.Errorf("Unexpected error: failed to marshal response: %v", err))
 	}
+	return string(b)
 }
 
 // processGetSchema returns the schema JSON string.
@@ -86,3 +99,85 @@ func processGetSchema() string {
 
 	return string(b)
 }
+
+// yieldingContext sleeps for a moment every yieldInterval when its Err is
+// checked. A render checks the context on every write and range, and under
+// js/wasm only sleeping lets the JS event loop run, e.g. to deliver the abort
+// event canceling the render. It is meant for a single render goroutine.
+type yieldingContext struct {
+	context.Context
+	last time.Time
+}
+
+func newYieldingContext(ctx context.Context) *yieldingContext {
+	return &yieldingContext{Context: ctx, last: time.Now()}
+}
+
+func (c *yieldingContext) Err() error {
+	if time.Since(c.last) >= yieldInterval {
+		time.Sleep(time.Millisecond)
+		c.last = time.Now()
+	}
+	return c.Context.Err()
+}
+
+// templateSource is a built-in template with its metadata.
+type templateSource struct {
+	templates.TemplateInfo
+	Source string `json:"source"`
+}
+
+// fixtureData is an example payload, as used by the tests.
+type fixtureData struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+// processListTemplates returns metadata of the built-in templates as a JSON string.
+func processListTemplates() string {
+	list, err := templates.List()
+	if err != nil {
+		return resultErrWrapper(err)
+	}
+	return marshalResult(list)
+}
+
+// processGetTemplate returns the named built-in template with its metadata as a JSON string.
+func processGetTemplate(name string) string {
+	info, err := templates.Get(name)
+	if err != nil {
+		return resultErrWrapper(err)
+	}
+	source, err := templates.Asset(name)
+	if err != nil {
+		return resultErrWrapper(err)
+	}
+	return marshalResult(templateSource{TemplateInfo: info, Source: string(source)})
+}
+
+// processListFixtures returns names of the example payloads as a JSON string.
+func processListFixtures() string {
+	names := make([]string, 0, len(render.TestingViewModels))
+	for name := range render.TestingViewModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return marshalResult(names)
+}
+
+// processGetFixture returns the named example payload as a JSON string.
+func processGetFixture(name string) string {
+	data, ok := render.TestingViewModels[name]
+	if !ok {
+		return resultErrWrapper(fmt.Errorf("fixture %q not found", name))
+	}
+	return marshalResult(fixtureData{Name: name, Data: data})
+}
+
+func marshalResult(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return resultErrWrapper(fmt.Errorf("Unexpected error: failed to marshal response: %v", err))
+	}
+	return string(b)
+}
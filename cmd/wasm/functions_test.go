@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/kentik/custom-notification-templates/pkg/render"
 )
 
 func TestProcessRender(t *testing.T) {
@@ -31,6 +35,7 @@ func TestProcessRender(t *testing.T) {
 		name           string
 		template       string
 		data           string
+		json           bool
 		wantErr        bool
 		errContains    string
 		validateResult func(*testing.T, string)
@@ -97,6 +102,17 @@ func TestProcessRender(t *testing.T) {
 			data:        "{invalid json}",
 			wantErr:     true,
 			errContains: "Data parse error",
+			validateResult: func(t *testing.T, fullResponse string) {
+				var resp map[string]interface{}
+				json.Unmarshal([]byte(fullResponse), &resp)
+
+				if resp["errorKind"] != "data" {
+					t.Errorf("Expected errorKind data, got %v", resp["errorKind"])
+				}
+				if resp["pane"] != "data" || resp["startColumn"] != float64(2) {
+					t.Errorf("Expected error located at column 2 of data, got %v:%v", resp["pane"], resp["startColumn"])
+				}
+			},
 		},
 		{
 			name:     "Empty template",
@@ -135,6 +151,17 @@ func TestProcessRender(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "JSON output is escaped",
+			template: `{"name": "{{ .CompanyName }}"}`,
+			data:     `{"CompanyName": "Say \"hi\"", "CompanyID": 1}`,
+			json:     true,
+			validateResult: func(t *testing.T, output string) {
+				if output != `{"name": "Say \"hi\""}` {
+					t.Errorf("Expected the company name to be escaped, got '%s'", output)
+				}
+			},
+		},
 		{
 			name:     "importanceToColor function",
 			template: "{{ importanceToColor .Event.Importance }}",
@@ -152,7 +179,7 @@ func TestProcessRender(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resJSON := processRender(tt.template, tt.data)
+			resJSON := processRender(tt.template, tt.data, tt.json)
 
 			// processRender returns a JSON string representing RenderResponse OR an error wrapper.
 			// We need to parse it to check for error.
@@ -300,3 +327,105 @@ func TestProcessGetSchema(t *testing.T) {
 		t.Error("Missing EventType enum")
 	}
 }
+
+func TestProcessTemplates(t *testing.T) {
+	var list []struct {
+		Name     string `json:"name"`
+		Title    string `json:"title"`
+		Platform string `json:"platform"`
+	}
+	if err := json.Unmarshal([]byte(processListTemplates()), &list); err != nil {
+		t.Fatalf("Failed to unmarshal template list: %v", err)
+	}
+	found := false
+	for _, info := range list {
+		if info.Name == "pagerduty.json.tmpl" {
+			found = info.Platform == "pagerduty" && info.Title != ""
+		}
+	}
+	if !found {
+		t.Errorf("Template list should describe pagerduty.json.tmpl, got %+v", list)
+	}
+
+	var tmpl struct {
+		Name   string `json:"name"`
+		Source string `json:"source"`
+	}
+	if err := json.Unmarshal([]byte(processGetTemplate("pagerduty.json.tmpl")), &tmpl); err != nil {
+		t.Fatalf("Failed to unmarshal template: %v", err)
+	}
+	source, _ := os.ReadFile("../../templates/pagerduty.json.tmpl")
+	if tmpl.Name != "pagerduty.json.tmpl" || tmpl.Source != string(source) {
+		t.Errorf("Expected source of pagerduty.json.tmpl, got %+v", tmpl)
+	}
+
+	if res := processGetTemplate("missing.tmpl"); !strings.Contains(res, `"error"`) {
+		t.Errorf("Expected error for missing template, got %s", res)
+	}
+}
+
+func TestProcessFixtures(t *testing.T) {
+	var names []string
+	if err := json.Unmarshal([]byte(processListFixtures()), &names); err != nil {
+		t.Fatalf("Failed to unmarshal fixture list: %v", err)
+	}
+	if strings.Join(names, ",") != "alarm,digest,insight,mitigation,synthetics" {
+		t.Errorf("Unexpected fixtures: %v", names)
+	}
+
+	var fixture struct {
+		Name string          `json:"name"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(processGetFixture("alarm")), &fixture); err != nil {
+		t.Fatalf("Failed to unmarshal fixture: %v", err)
+	}
+	if fixture.Name != "alarm" || !strings.Contains(string(fixture.Data), "CompanyName") {
+		t.Errorf("Unexpected alarm fixture: %+v", fixture)
+	}
+
+	var resp struct {
+		Output string `json:"output"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(processRenderFixture("{{ .CompanyName }}", "alarm")), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal render response: %v", err)
+	}
+	if resp.Error != "" || resp.Output != "ACME Incorporated" {
+		t.Errorf("Unexpected render result: %+v", resp)
+	}
+
+	for _, res := range []string{processGetFixture("missing"), processRenderFixture("{{ . }}", "missing")} {
+		if !strings.Contains(res, `fixture \"missing\" not found`) {
+			t.Errorf("Expected error for missing fixture, got %s", res)
+		}
+	}
+}
+
+func TestProcessRenderContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	yielding := newYieldingContext(ctx)
+	yielding.last = time.Now().Add(-yieldInterval)
+	if yielding.Err() != nil || !yielding.last.After(time.Now().Add(-yieldInterval)) {
+		t.Errorf("Expected yielding context to yield and report no error")
+	}
+	if _, ok := yielding.Deadline(); !ok {
+		t.Errorf("Expected yielding context to keep the deadline")
+	}
+	cancel()
+
+	var resp struct {
+		Error     string `json:"error"`
+		ErrorKind string `json:"errorKind"`
+	}
+	res := processRenderContext(yielding, render.RenderRequest{
+		Template: "{{ range .Events }}{{ . }}{{ end }}",
+		Data:     render.TestingViewModels["alarm"],
+	})
+	if err := json.Unmarshal([]byte(res), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal render response: %v", err)
+	}
+	if resp.ErrorKind != render.ErrorKind_Limit || !strings.Contains(resp.Error, "canceled") {
+		t.Errorf("Expected canceled render, got %+v", resp)
+	}
+}
@@ -3,21 +3,109 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"syscall/js"
+	"time"
+
+	"github.com/kentik/custom-notification-templates/pkg/render"
 )
 
+// renders the template, taking an optional options object: {json: boolean
+// rendering JSON output, with values escaped for their place in the JSON}
 func renderTemplate(this js.Value, args []js.Value) (result any) {
 	// JS-specific validation
 	if len(args) < 2 {
-		return resultErrWrapper(fmt.Errorf("Expected arguments: (template: string, dataJson: string)"))
+		return resultErrWrapper(fmt.Errorf("Expected arguments: (template: string, dataJson: string, options?: object)"))
 	}
 
 	if args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
 		return resultErrWrapper(fmt.Errorf("Arguments must be strings"))
 	}
 
-	return processRender(args[0].String(), args[1].String())
+	jsonOutput := len(args) > 2 && args[2].Type() == js.TypeObject && args[2].Get("json").Truthy()
+	return processRender(args[0].String(), args[1].String(), jsonOutput)
+}
+
+// renders the template in the background and returns a Promise of the result,
+// taking an optional options object: {timeout: milliseconds, signal: AbortSignal,
+// params: object with template parameters, palette: object overriding importance styles,
+// json: boolean rendering JSON output}.
+// The Promise is rejected with signal.reason when the signal aborts the render.
+func renderTemplateAsync(this js.Value, args []js.Value) (result any) {
+	if len(args) < 2 {
+		return resolved(resultErrWrapper(fmt.Errorf("Expected arguments: (template: string, dataJson: string, options?: object)")))
+	}
+
+	if args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+		return resolved(resultErrWrapper(fmt.Errorf("Arguments must be strings")))
+	}
+
+	req := render.RenderRequest{
+		Template: args[0].String(),
+		Data:     json.RawMessage(args[1].String()),
+		Preview:  true,
+	}
+	timeout := renderTimeout
+	signal := js.Undefined()
+	if len(args) > 2 && args[2].Type() == js.TypeObject {
+		if t := args[2].Get("timeout"); t.Type() == js.TypeNumber && t.Float() > 0 {
+			timeout = time.Duration(t.Float() * float64(time.Millisecond))
+		}
+		signal = args[2].Get("signal")
+		req.JSON = args[2].Get("json").Truthy()
+		if params := args[2].Get("params"); params.Type() == js.TypeObject {
+			paramsJSON := js.Global().Get("JSON").Call("stringify", params).String()
+			if err := json.Unmarshal([]byte(paramsJSON), &req.Params); err != nil {
+				return resolved(resultErrWrapper(fmt.Errorf("Invalid params: %v", err)))
+			}
+		}
+		if palette := args[2].Get("palette"); palette.Type() == js.TypeObject {
+			paletteJSON := js.Global().Get("JSON").Call("stringify", palette).String()
+			if err := json.Unmarshal([]byte(paletteJSON), &req.Palette); err != nil {
+				return resolved(resultErrWrapper(fmt.Errorf("Invalid palette: %v", err)))
+			}
+		}
+	}
+
+	executor := js.FuncOf(func(this js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if signal.Truthy() {
+				if signal.Get("aborted").Truthy() {
+					reject.Invoke(signal.Get("reason"))
+					return
+				}
+				onAbort := js.FuncOf(func(this js.Value, args []js.Value) any {
+					cancel()
+					return nil
+				})
+				defer onAbort.Release()
+				signal.Call("addEventListener", "abort", onAbort)
+				defer signal.Call("removeEventListener", "abort", onAbort)
+			}
+
+			res := processRenderContext(newYieldingContext(ctx), req)
+			if signal.Truthy() && signal.Get("aborted").Truthy() {
+				reject.Invoke(signal.Get("reason"))
+				return
+			}
+			resolve.Invoke(res)
+		}()
+		return nil
+	})
+	// the executor runs synchronously within the Promise constructor
+	defer executor.Release()
+	return js.Global().Get("Promise").New(executor)
+}
+
+// resolved returns a Promise resolved with the value.
+func resolved(value any) js.Value {
+	return js.Global().Get("Promise").Call("resolve", value)
 }
 
 // returns the complete schema for template editing, with available fields
@@ -25,8 +113,53 @@ func getSchema(this js.Value, args []js.Value) (result any) {
 	return processGetSchema()
 }
 
+// renders the template against one of the example payloads
+func renderFixture(this js.Value, args []js.Value) (result any) {
+	if len(args) < 2 {
+		return resultErrWrapper(fmt.Errorf("Expected arguments: (template: string, fixture: string)"))
+	}
+
+	if args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+		return resultErrWrapper(fmt.Errorf("Arguments must be strings"))
+	}
+
+	return processRenderFixture(args[0].String(), args[1].String())
+}
+
+// returns metadata of the built-in templates
+func listTemplates(this js.Value, args []js.Value) (result any) {
+	return processListTemplates()
+}
+
+// returns the source and metadata of a built-in template
+func getTemplate(this js.Value, args []js.Value) (result any) {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return resultErrWrapper(fmt.Errorf("Expected arguments: (name: string)"))
+	}
+	return processGetTemplate(args[0].String())
+}
+
+// returns names of the example payloads
+func listFixtures(this js.Value, args []js.Value) (result any) {
+	return processListFixtures()
+}
+
+// returns one of the example payloads
+func getFixture(this js.Value, args []js.Value) (result any) {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return resultErrWrapper(fmt.Errorf("Expected arguments: (name: string)"))
+	}
+	return processGetFixture(args[0].String())
+}
+
 func main() {
 	js.Global().Set("goTemplateRender", js.FuncOf(renderTemplate))
+	js.Global().Set("goTemplateRenderAsync", js.FuncOf(renderTemplateAsync))
 	js.Global().Set("goTemplateGetSchema", js.FuncOf(getSchema))
+	js.Global().Set("goTemplateRenderFixture", js.FuncOf(renderFixture))
+	js.Global().Set("goTemplateList", js.FuncOf(listTemplates))
+	js.Global().Set("goTemplateGet", js.FuncOf(getTemplate))
+	js.Global().Set("goFixtureList", js.FuncOf(listFixtures))
+	js.Global().Set("goFixtureGet", js.FuncOf(getFixture))
 	select {}
 }
@@ -0,0 +1,204 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// jsonValue is a value found in a JSON document, identified by its JSONPath.
+type jsonValue struct {
+	path       string
+	start, end int // byte offsets, end is -1 for values cut short by a syntax error
+}
+
+// jsonIndexer records the location of every value in a JSON document. It
+// stops at the first syntax error, leaving the values around it incomplete.
+type jsonIndexer struct {
+	data   []byte
+	pos    int
+	values []jsonValue
+}
+
+func indexJSON(data []byte) []jsonValue {
+	ix := &jsonIndexer{data: data}
+	ix.value("$")
+	return ix.values
+}
+
+func (ix *jsonIndexer) skipSpace() {
+	for ix.pos < len(ix.data) && strings.IndexByte(" \t\r\n", ix.data[ix.pos]) >= 0 {
+		ix.pos++
+	}
+}
+
+func (ix *jsonIndexer) peek() byte {
+	ix.skipSpace()
+	if ix.pos >= len(ix.data) {
+		return 0
+	}
+	return ix.data[ix.pos]
+}
+
+func (ix *jsonIndexer) value(path string) bool {
+	ix.skipSpace()
+	idx := len(ix.values)
+	ix.values = append(ix.values, jsonValue{path: path, start: ix.pos, end: -1})
+
+	var ok bool
+	switch ix.peek() {
+	case '{':
+		ok = ix.object(path)
+	case '[':
+		ok = ix.array(path)
+	case '"':
+		_, ok = ix.str()
+	case 0:
+		ok = false
+	default:
+		ok = ix.literal()
+	}
+	if ok {
+		ix.values[idx].end = ix.pos
+	}
+	return ok
+}
+
+func (ix *jsonIndexer) object(path string) bool {
+	ix.pos++
+	if ix.peek() == '}' {
+		ix.pos++
+		return true
+	}
+	for {
+		if ix.peek() != '"' {
+			return false
+		}
+		key, ok := ix.str()
+		if !ok || ix.peek() != ':' {
+			return false
+		}
+		ix.pos++
+		if !ix.value(jsonPathKey(path, key)) {
+			return false
+		}
+		switch ix.peek() {
+		case ',':
+			ix.pos++
+		case '}':
+			ix.pos++
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func (ix *jsonIndexer) array(path string) bool {
+	ix.pos++
+	if ix.peek() == ']' {
+		ix.pos++
+		return true
+	}
+	for i := 0; ; i++ {
+		if !ix.value(path + "[" + strconv.Itoa(i) + "]") {
+			return false
+		}
+		switch ix.peek() {
+		case ',':
+			ix.pos++
+		case ']':
+			ix.pos++
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func (ix *jsonIndexer) str() (string, bool) {
+	start := ix.pos
+	for ix.pos++; ix.pos < len(ix.data); ix.pos++ {
+		switch ix.data[ix.pos] {
+		case '\\':
+			ix.pos++
+		case '"':
+			ix.pos++
+			var res string
+			err := json.Unmarshal(ix.data[start:ix.pos], &res)
+			return res, err == nil
+		}
+	}
+	return "", false
+}
+
+func (ix *jsonIndexer) literal() bool {
+	start := ix.pos
+	for ix.pos < len(ix.data) && strings.IndexByte(" \t\r\n,:]}[{\"", ix.data[ix.pos]) < 0 {
+		ix.pos++
+	}
+	return ix.pos > start
+}
+
+func jsonPathKey(path, key string) string {
+	for i, c := range key {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return path + "[" + strconv.Quote(key) + "]"
+		}
+	}
+	if key == "" {
+		return path + `[""]`
+	}
+	return path + "." + key
+}
+
+// locateDataPath finds the byte range of the value at the JSONPath.
+func locateDataPath(data []byte, path string) (start, end int, ok bool) {
+	for _, v := range indexJSON(data) {
+		if v.path == path && v.end >= 0 {
+			return v.start, v.end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// locateDataError finds the byte range and JSONPath of the value a decoding error refers to.
+func locateDataError(data []byte, err error) (start, end int, path string, ok bool) {
+	values := indexJSON(data)
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset := max(0, min(int(syntaxErr.Offset)-1, len(data)))
+		for _, v := range values {
+			if v.start <= offset && (v.end < 0 || v.end > offset) {
+				path = v.path
+			}
+		}
+		return offset, min(offset+1, len(data)), path, true
+
+	case errors.As(err, &typeErr):
+		// custom unmarshalers report offsets relative to the object they decode,
+		// so try every object as the base and match the field name
+		field := typeErr.Field
+		if i := strings.LastIndex(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		for _, base := range values {
+			if base.end < 0 || data[base.start] != '{' {
+				continue
+			}
+			for _, v := range values {
+				if v.end == base.start+int(typeErr.Offset) && strings.HasPrefix(v.path, base.path) &&
+					(field == "" || strings.HasSuffix(v.path, "."+field) || strings.HasSuffix(v.path, "["+strconv.Quote(field)+"]")) {
+					return v.start, v.end, v.path, true
+				}
+			}
+		}
+	}
+	return 0, 0, "", false
+}
@@ -0,0 +1,195 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error kinds reported in RenderResponse.ErrorKind.
+const (
+	ErrorKind_Parse  = "parse"
+	ErrorKind_Exec   = "exec"
+	ErrorKind_Data   = "data"
+	ErrorKind_Limit  = "limit"
+	ErrorKind_Output = "output"
+	ErrorKind_Param  = "param"
+)
+
+var (
+	parseErrorRegex = regexp.MustCompile(`^template:\s*([^:]+):(\d+):`)
+	execErrorRegex  = regexp.MustCompile(`^template:\s*([^:]+):(?:(\d+):(\d+):)?\s*executing "([^"]*)" at <(.*?)>: `)
+)
+
+// ParseError reports a template (or one of its sections) that cannot be parsed.
+type ParseError struct {
+	Name  string
+	Line  int
+	Range Range
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ExecError reports a failure while executing a parsed template. Name is the
+// template or define block being executed and Action the failing action.
+// Source is the template or section whose text Line, Column and Range refer to.
+type ExecError struct {
+	Name   string
+	Source string
+	Line   int
+	Column int
+	Action string
+	Range  Range
+	Err    error
+}
+
+func (e *ExecError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// DataError reports a payload that cannot be decoded into NotificationViewModel.
+// Path (a JSONPath) and Range locate the offending value in the payload, when known.
+type DataError struct {
+	Path  string
+	Range Range
+	Err   error
+}
+
+func (e *DataError) Error() string {
+	return "Data parse error: " + e.Err.Error()
+}
+
+func (e *DataError) Unwrap() error {
+	return e.Err
+}
+
+// OutputError reports rendered output which is not valid for the template,
+// such as output of a .json.tmpl template which does not parse as JSON, or
+// which does not match the payload schema of the target platform. Path (a
+// JSONPath) and Range locate the problem in the output, when known.
+type OutputError struct {
+	Name  string
+	Path  string
+	Range Range
+	Err   error
+}
+
+func (e *OutputError) Error() string {
+	return "Output error: " + e.Err.Error()
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// isJSONTemplate tells whether output of a template named name must be JSON,
+// like that of a JSON request. Its values are not escaped unless requested.
+func isJSONTemplate(name string) bool {
+	return strings.HasSuffix(name, ".json.tmpl")
+}
+
+// validateJSON checks that output of the named template is valid JSON.
+func validateJSON(name, output string) error {
+	var value interface{}
+	err := json.Unmarshal([]byte(output), &value)
+	if err == nil {
+		return nil
+	}
+
+	res := &OutputError{Name: name, Err: err}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Offset > 0 {
+		res.Range = rangeOf(output, int(syntaxErr.Offset)-1, int(syntaxErr.Offset))
+	}
+	return res
+}
+
+// newDataError wraps an error returned by decoding data.
+func newDataError(data []byte, err error) *DataError {
+	res := &DataError{Err: err}
+	if start, end, path, ok := locateDataError(data, err); ok {
+		res.Path = path
+		res.Range = rangeOf(string(data), start, end)
+	}
+	return res
+}
+
+// newParseError wraps an error returned by parsing text as the named template.
+func newParseError(name, text string, err error) *ParseError {
+	res := &ParseError{Name: name, Err: err}
+	if matches := parseErrorRegex.FindStringSubmatch(err.Error()); matches != nil {
+		res.Name = matches[1]
+		res.Line, _ = strconv.Atoi(matches[2])
+		res.Range = parseErrorRange(text, res.Line, err.Error())
+	}
+	return res
+}
+
+// newExecError wraps an error returned by template execution. Limits hit
+// during execution keep their location but hide the internals of the guard.
+func newExecError(name string, err error) *ExecError {
+	res := &ExecError{Name: name, Source: name, Err: err}
+	if matches := execErrorRegex.FindStringSubmatch(err.Error()); matches != nil {
+		res.Source = matches[1]
+		res.Name = matches[4]
+		res.Line, _ = strconv.Atoi(matches[2])
+		res.Column, _ = strconv.Atoi(matches[3])
+		res.Action = matches[5]
+	}
+
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		res.Action = ""
+		res.Err = limitErr
+	}
+	return res
+}
+
+// skipError stops execution of a template calling skip, it is not reported as an error.
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return "skipped: " + e.reason
+}
+
+// errorKind classifies err into one of the ErrorKind_* values.
+func errorKind(err error) string {
+	var (
+		parseErr  *ParseError
+		execErr   *ExecError
+		dataErr   *DataError
+		limitErr  *LimitError
+		outputErr *OutputError
+		paramErr  *ParamError
+	)
+	switch {
+	case errors.As(err, &limitErr):
+		return ErrorKind_Limit
+	case errors.As(err, &dataErr):
+		return ErrorKind_Data
+	case errors.As(err, &paramErr):
+		return ErrorKind_Param
+	case errors.As(err, &parseErr):
+		return ErrorKind_Parse
+	case errors.As(err, &execErr):
+		return ErrorKind_Exec
+	case errors.As(err, &outputErr):
+		return ErrorKind_Output
+	}
+	return ""
+}
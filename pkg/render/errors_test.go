@@ -0,0 +1,83 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_ErrorTaxonomy(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        RenderRequest
+		opts       RenderOptions
+		wantKind   string
+		wantName   string
+		wantAction string
+		wantLine   int
+		target     interface{}
+	}{
+		{
+			name:     "Parse error",
+			req:      RenderRequest{Name: "body", Template: "Line 1\n{{ .Invalid", Data: TestingViewModels["alarm"]},
+			wantKind: ErrorKind_Parse,
+			wantName: "body",
+			wantLine: 2,
+			target:   new(*ParseError),
+		},
+		{
+			name:       "Exec error",
+			req:        RenderRequest{Template: "{{ .Event.Foo }}", Data: TestingViewModels["alarm"]},
+			wantKind:   ErrorKind_Exec,
+			wantName:   "template",
+			wantAction: ".Event.Foo",
+			wantLine:   1,
+			target:     new(*ExecError),
+		},
+		{
+			name:       "Exec error in define block",
+			req:        RenderRequest{Template: "{{ define \"x\" }}\n{{ .Event.Foo }}{{ end }}{{ template \"x\" . }}", Data: TestingViewModels["alarm"]},
+			wantKind:   ErrorKind_Exec,
+			wantName:   "x",
+			wantAction: ".Event.Foo",
+			wantLine:   2,
+			target:     new(*ExecError),
+		},
+		{
+			name:     "Data error",
+			req:      RenderRequest{Template: "{{ .CompanyID }}", Data: []byte(`{"CompanyID": "x"}`)},
+			wantKind: ErrorKind_Data,
+			target:   new(*DataError),
+		},
+		{
+			name:     "Limit error",
+			req:      RenderRequest{Template: "{{ range 10 }}{{ end }}", Data: TestingViewModels["alarm"]},
+			opts:     RenderOptions{MaxRangeIterations: 5},
+			wantKind: ErrorKind_Limit,
+			wantName: "template",
+			wantLine: 1,
+			target:   new(*LimitError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := RenderContext(context.Background(), tt.req, tt.opts)
+			if resp.ErrorKind != tt.wantKind {
+				t.Errorf("Expected kind %q, got %q (%s)", tt.wantKind, resp.ErrorKind, resp.Error)
+			}
+			if resp.TemplateName != tt.wantName {
+				t.Errorf("Expected template name %q, got %q", tt.wantName, resp.TemplateName)
+			}
+			if resp.Action != tt.wantAction {
+				t.Errorf("Expected action %q, got %q", tt.wantAction, resp.Action)
+			}
+			if resp.Line != tt.wantLine {
+				t.Errorf("Expected line %d, got %d", tt.wantLine, resp.Line)
+			}
+			if !errors.As(resp.Err(), tt.target) {
+				t.Errorf("Expected %T, got %T", tt.target, resp.Err())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unknownFields returns members of the JSON object data which encoding/json
+// would not decode into any field of aux, matching names case-insensitively
+// like encoding/json does. Payloads from newer notification engines are
+// accepted and their new fields are kept as Extra.
+func unknownFields(data []byte, aux interface{}) (map[string]interface{}, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+
+	names := jsonFieldNames(reflect.TypeOf(aux))
+	var res map[string]interface{}
+	for key, raw := range members {
+		if slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, key) }) {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = make(map[string]interface{})
+		}
+		res[key] = value
+	}
+	return res, nil
+}
+
+// jsonFieldNames lists the object keys encoding/json decodes into the struct
+// type t, including fields of embedded structs.
+func jsonFieldNames(t reflect.Type) []string {
+	t = derefType(t)
+	var res []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+		case field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct:
+			res = append(res, jsonFieldNames(field.Type)...)
+		case !field.IsExported():
+		case name == "":
+			res = append(res, field.Name)
+		default:
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// unknownField is a payload member kept in an Extra map.
+type unknownField struct {
+	path string
+	key  string
+}
+
+// unknownFieldsOf lists the Extra members of the view model, its config, its
+// events and their details by JSONPath, in payload order of events and
+// details and key order within objects.
+func unknownFieldsOf(vm *NotificationViewModel) []unknownField {
+	var res []unknownField
+	add := func(path string, extra map[string]interface{}) {
+		keys := make([]string, 0, len(extra))
+		for key := range extra {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			res = append(res, unknownField{path: jsonPathKey(path, key), key: key})
+		}
+	}
+
+	add("$", vm.Extra)
+	if vm.Config != nil {
+		add("$.Config", vm.Config.Extra)
+	}
+	for i, event := range vm.RawEvents {
+		if event == nil {
+			continue
+		}
+		path := "$.Events[" + strconv.Itoa(i) + "]"
+		add(path, event.Extra)
+		for j, detail := range event.Details {
+			if detail != nil {
+				add(path+".Details["+strconv.Itoa(j)+"]", detail.Extra)
+			}
+		}
+	}
+	return res
+}
+
+// unknownFieldError rejects a payload with unknown fields, in strict mode.
+func unknownFieldError(data []byte, field unknownField) *DataError {
+	res := &DataError{Path: field.path, Err: fmt.Errorf("json: unknown field %q", field.key)}
+	if start, end, ok := locateDataPath(data, field.path); ok {
+		res.Range = rangeOf(string(data), start, end)
+	}
+	return res
+}
+
+// unknownFieldWarnings reports unknown fields, kept as Extra, as warnings.
+func unknownFieldWarnings(data []byte, fields []unknownField) []Warning {
+	var res []Warning
+	for _, field := range fields {
+		warning := Warning{Message: fmt.Sprintf("unknown field %s", field.key), Pane: Pane_Data, DataPath: field.path}
+		if start, end, ok := locateDataPath(data, field.path); ok {
+			warning.Range = rangeOf(string(data), start, end)
+		}
+		res = append(res, warning)
+	}
+	return res
+}
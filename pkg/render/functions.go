@@ -1,9 +1,12 @@
 package render
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -36,6 +39,103 @@ func split(s string, sep string) []string {
 	return strings.Split(s, sep)
 }
 
+// param returns the template parameter passed with the render request under
+// name, or def when it is not passed. Example: param "routing_key" "default-key".
+// Category: utility
+func param(name string, def interface{}) interface{} {
+	return def
+}
+
+// skip stops rendering and marks the notification as intentionally not sent.
+// Example: {{ if .IsInsightsOnly }}{{ skip "insights are not forwarded" }}{{ end }}.
+// Category: utility
+func skip(reason string) (string, error) {
+	return "", &skipError{reason: reason}
+}
+
+// dedupKey returns the DedupKey of the event, hashed to the given number of
+// hex digits (at most 64) if passed. Example: {{ dedupKey .Event 32 }}.
+// Category: utility
+func dedupKey(event *EventViewModel, length ...int) (string, error) {
+	if event == nil {
+		return "", nil
+	}
+	key := event.DedupKey()
+	if len(length) == 0 || key == "" {
+		return key, nil
+	}
+	if len(length) > 1 || length[0] < 1 || length[0] > sha256.Size*2 {
+		return "", fmt.Errorf("dedupKey length must be between 1 and %d", sha256.Size*2)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:length[0]], nil
+}
+
+// incidentAction returns the action an incident management platform (pagerduty,
+// opsgenie or servicenow) expects for the transition of the event.
+// Example: "event_action": "{{ incidentAction "pagerduty" .Event }}".
+// Category: utility
+func incidentAction(platform string, event *EventViewModel) (string, error) {
+	actions, ok := incidentActions[platform]
+	if !ok {
+		platforms := make([]string, 0, len(incidentActions))
+		for name := range incidentActions {
+			platforms = append(platforms, name)
+		}
+		sort.Strings(platforms)
+		return "", fmt.Errorf("unknown incident platform %q, expected one of: %s", platform, strings.Join(platforms, ", "))
+	}
+	if event == nil {
+		return "", fmt.Errorf("incidentAction needs an event")
+	}
+	return actions[event.Transition()], nil
+}
+
+// mapSeverity maps an importance level or an AlarmSeverity detail value to the
+// severity of a platform (pagerduty, opsgenie, servicenow, splunk-oncall, syslog
+// or cef), overrides replacing individual entries of SeverityMappings, the last one winning.
+// Example: {{ mapSeverity "opsgenie" .Importance "minor=P3,notice=P4" }}.
+// Category: formatting
+func mapSeverity(platform string, severity interface{}, overrides ...interface{}) (string, error) {
+	mapping, ok := SeverityMappings[platform]
+	if !ok {
+		platforms := make([]string, 0, len(SeverityMappings))
+		for name := range SeverityMappings {
+			platforms = append(platforms, name)
+		}
+		sort.Strings(platforms)
+		return "", fmt.Errorf("unknown severity platform %q, expected one of: %s", platform, strings.Join(platforms, ", "))
+	}
+	level := severityLevel(severity)
+	res := mapping[level]
+	for _, override := range overrides {
+		entries, err := severityOverrides(override)
+		if err != nil {
+			return "", err
+		}
+		if value, ok := entries[level]; ok {
+			res = value
+		}
+	}
+	return res, nil
+}
+
+// formatUnit formats a value in a unit (bits/s, B/s, pps, flows/s, %, ms or count),
+// with SI prefixes or, for bits and bytes, IEC ones if the system is iec.
+// Example: {{ formatUnit $detail.Value $detail.Unit }} gives 58.56 kbits/s.
+// Category: formatting
+func formatUnit(value interface{}, unit string, system ...string) (string, error) {
+	floatValue, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	unitSystem, err := checkUnitSystem(system)
+	if err != nil {
+		return "", err
+	}
+	return joinUnit(formatValue(floatValue, unit, unitSystem)), nil
+}
+
 var TextTemplateFuncMap = template.FuncMap{
 	"toUpper":   toUpper,
 	"title":     title,
@@ -50,10 +150,18 @@ var TextTemplateFuncMap = template.FuncMap{
 	"timeRfc3339":     timeRfc3339,
 	"join":            join,
 	"joinWith":        joinWith,
+	"param":           param,
+	"skip":            skip,
+	"dedupKey":        dedupKey,
+	"incidentAction":  incidentAction,
+	"mapSeverity":     mapSeverity,
+	"formatUnit":      formatUnit,
 
 	"importanceLabel":   importanceLabel,
 	"importanceToColor": importanceToColor,
 	"importanceToEmoji": importanceToEmoji,
+	"importanceColor":   importanceColor,
+	"importanceEmoji":   importanceEmoji,
 }
 
 func tryParseTime(input string) (time.Time, error) {
@@ -180,6 +288,22 @@ func importanceLabel(severity ViewModelImportance) string {
 	return cases.Title(language.English).String(importanceName(severity))
 }
 
+// importanceColor returns the color of an importance level as a platform expects it:
+// hex (also slack, mattermost and webex), discord (decimal integer) or msteams
+// (Adaptive Card container style). Example: "color": {{ importanceColor "discord" .Importance }}.
+// Category: formatting
+func importanceColor(platform string, severity ViewModelImportance) (interface{}, error) {
+	return DefaultPalette.color(platform, severity)
+}
+
+// importanceEmoji returns the emoji(s) for an importance level as Slack shortcodes
+// (slack, mattermost) or Unicode characters (unicode, also telegram, webex, msteams and discord).
+// Example: {{ importanceEmoji "unicode" .Importance }}.
+// Category: formatting
+func importanceEmoji(platform string, severity ViewModelImportance) (string, error) {
+	return DefaultPalette.emoji(platform, severity)
+}
+
 // importanceToEmoji returns the emoji(s) for an importance level.
 // Category: formatting
 func importanceToEmoji(severity ViewModelImportance) string {
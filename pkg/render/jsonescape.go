@@ -0,0 +1,324 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Names of the escaping functions appended to actions of JSON templates.
+const (
+	jsonStringFunc = "_render_jsonstr"
+	jsonValueFunc  = "_render_jsonval"
+	jsonKeyFunc    = "_render_jsonkey"
+)
+
+// rawJSONFuncs produce JSON text, outside of strings their results are printed as is.
+var rawJSONFuncs = map[string]bool{
+	"toJSON":          true,
+	"j":               true,
+	"uglifyJSON":      true,
+	"explodeJSONKeys": true,
+	"x":               true,
+}
+
+// separatorFuncs print separators given by the template, their results are never escaped.
+var separatorFuncs = map[string]bool{
+	"join":     true,
+	"joinWith": true,
+}
+
+// jsonState is where in the JSON output the template is.
+type jsonState uint8
+
+const (
+	jsonStateValue  jsonState = iota // outside of strings: values, keys and punctuation
+	jsonStateString                  // inside a string
+	jsonStateEscape                  // inside a string, right after a backslash
+)
+
+// jsonEscaper makes every action of a JSON template escape its value for the
+// place it is printed to, like html/template does for HTML: inside strings
+// values are escaped as string contents, elsewhere they are printed as JSON
+// values, or strings when followed by a colon (keys). Output of rawJSONFuncs
+// is trusted outside of strings, separators and constants anywhere.
+type jsonEscaper struct {
+	tmpl    *template.Template
+	sources map[string]string
+	started map[string]jsonState // state at the start of called define blocks
+	ended   map[string]jsonState // state at their end
+	err     error
+}
+
+// escapeJSON rewrites the named templates, and templates they call, to escape
+// their output. It returns a *ParseError when a template does not keep to
+// a single state, e.g. when branches of an if end in different states, or
+// when a template it rewrites is also called from one of the plain templates.
+func escapeJSON(tmpl *template.Template, names, plain []string, sources map[string]string) error {
+	e := &jsonEscaper{
+		tmpl:    tmpl,
+		sources: sources,
+		started: make(map[string]jsonState),
+		ended:   make(map[string]jsonState),
+	}
+	for _, name := range names {
+		t := tmpl.Lookup(name)
+		if t == nil || t.Tree == nil {
+			continue
+		}
+		if started, ok := e.started[name]; ok {
+			// already escaped as called from another JSON template
+			if started != jsonStateValue {
+				e.fail(t.Tree, t.Tree.Root, fmt.Sprintf("template %q is called in different JSON contexts", name))
+			}
+			continue
+		}
+		e.started[name] = jsonStateValue
+		e.ended[name] = e.list(t.Tree, t.Tree.Root, jsonStateValue)
+	}
+
+	visited := make(map[string]bool)
+	for _, name := range plain {
+		e.plain(name, visited)
+	}
+	return e.err
+}
+
+// plain checks that templates called from the named plain template, which
+// is printed as is, are not escaped for JSON.
+func (e *jsonEscaper) plain(name string, visited map[string]bool) {
+	t := e.tmpl.Lookup(name)
+	if visited[name] || t == nil || t.Tree == nil {
+		return
+	}
+	visited[name] = true
+	walkTree(t.Tree.Root, func(node parse.Node) {
+		n, ok := node.(*parse.TemplateNode)
+		if !ok {
+			return
+		}
+		if _, ok := e.started[n.Name]; ok {
+			e.fail(t.Tree, n, fmt.Sprintf("template %q is called from both JSON and plain output", n.Name))
+			return
+		}
+		e.plain(n.Name, visited)
+	})
+}
+
+func (e *jsonEscaper) list(tree *parse.Tree, list *parse.ListNode, state jsonState) jsonState {
+	if list == nil {
+		return state
+	}
+	for i, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			state = scanJSON(n.Text, state)
+		case *parse.ActionNode:
+			if text, ok := constantText(n.Pipe); ok {
+				state = scanJSON([]byte(text), state)
+			} else if len(n.Pipe.Decl) == 0 {
+				e.action(tree, n, state, list.Nodes[i+1:])
+			}
+		case *parse.IfNode:
+			state = e.branch(tree, n, &n.BranchNode, state, "if")
+		case *parse.WithNode:
+			state = e.branch(tree, n, &n.BranchNode, state, "with")
+		case *parse.RangeNode:
+			if end := e.list(tree, n.List, state); end != state {
+				e.fail(tree, n, "{{range}} body ends in a different JSON context than it starts")
+			}
+			if end := e.list(tree, n.ElseList, state); end != state {
+				e.fail(tree, n, "{{range}} branches end in different JSON contexts")
+			}
+		case *parse.TemplateNode:
+			state = e.call(tree, n, state)
+		}
+	}
+	return state
+}
+
+func (e *jsonEscaper) branch(tree *parse.Tree, node parse.Node, n *parse.BranchNode, state jsonState, keyword string) jsonState {
+	end := e.list(tree, n.List, state)
+	if elseEnd := e.list(tree, n.ElseList, state); elseEnd != end {
+		e.fail(tree, node, fmt.Sprintf("{{%s}} branches end in different JSON contexts", keyword))
+	}
+	return end
+}
+
+func (e *jsonEscaper) call(tree *parse.Tree, n *parse.TemplateNode, state jsonState) jsonState {
+	t := e.tmpl.Lookup(n.Name)
+	if t == nil || t.Tree == nil {
+		return state
+	}
+	if started, ok := e.started[n.Name]; ok {
+		if started != state {
+			e.fail(tree, n, fmt.Sprintf("template %q is called in different JSON contexts", n.Name))
+		}
+		if ended, ok := e.ended[n.Name]; ok {
+			return ended
+		}
+		// recursive call, assume the block ends where it starts
+		return state
+	}
+	e.started[n.Name] = state
+	e.ended[n.Name] = e.list(t.Tree, t.Tree.Root, state)
+	return e.ended[n.Name]
+}
+
+// action appends the escaping function for the state to the action pipeline.
+// The nodes following the action tell keys from values.
+func (e *jsonEscaper) action(tree *parse.Tree, n *parse.ActionNode, state jsonState, following []parse.Node) {
+	last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+	ident, _ := last.Args[0].(*parse.IdentifierNode)
+	if ident != nil && separatorFuncs[ident.Ident] {
+		return
+	}
+
+	var escaper string
+	switch state {
+	case jsonStateString:
+		escaper = jsonStringFunc
+	case jsonStateEscape:
+		e.fail(tree, n, "action follows a backslash in a JSON string")
+		return
+	default:
+		if ident != nil && rawJSONFuncs[ident.Ident] {
+			return
+		}
+		escaper = jsonValueFunc
+		if len(following) > 0 {
+			if text, ok := following[0].(*parse.TextNode); ok && strings.HasPrefix(strings.TrimLeft(string(text.Text), " \t\r\n"), ":") {
+				escaper = jsonKeyFunc
+			}
+		}
+	}
+	n.Pipe.Cmds = append(n.Pipe.Cmds, funcCommand(escaper, n.Pipe.Position()))
+}
+
+func (e *jsonEscaper) fail(tree *parse.Tree, node parse.Node, msg string) {
+	if e.err != nil {
+		return
+	}
+	location, _ := tree.ErrorContext(node)
+	err := fmt.Errorf("template: %s: %s", location, msg)
+	e.err = newParseError(tree.ParseName, e.sources[tree.ParseName], err)
+}
+
+// constantText returns the text printed by an action made of a single
+// constant, e.g. {{ "\\n" }}. Such actions are part of the template text.
+func constantText(pipe *parse.PipeNode) (string, bool) {
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.StringNode:
+		return arg.Text, true
+	case *parse.NumberNode:
+		return arg.Text, true
+	case *parse.BoolNode:
+		return arg.String(), true
+	}
+	return "", false
+}
+
+// scanJSON returns the state after text printed in the given state.
+func scanJSON(text []byte, state jsonState) jsonState {
+	for _, c := range text {
+		switch state {
+		case jsonStateValue:
+			if c == '"' {
+				state = jsonStateString
+			}
+		case jsonStateString:
+			switch c {
+			case '\\':
+				state = jsonStateEscape
+			case '"':
+				state = jsonStateValue
+			}
+		case jsonStateEscape:
+			state = jsonStateString
+		}
+	}
+	return state
+}
+
+// jsonArg returns the value piped into an escaping function, nil when missing.
+func jsonArg(args []interface{}) interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	return args[len(args)-1]
+}
+
+// jsonEscapeString escapes a value printed inside a JSON string.
+func jsonEscapeString(args ...interface{}) string {
+	v := jsonArg(args)
+	if v == nil {
+		return ""
+	}
+	quoted := jsonQuote(fmt.Sprint(v))
+	return quoted[1 : len(quoted)-1]
+}
+
+// jsonEscapeValue prints a value outside of strings as a JSON value: strings and
+// values printed as text (fmt.Stringer, error) are quoted, numbers and
+// booleans printed as usual and anything else marshaled.
+func jsonEscapeValue(args ...interface{}) string {
+	v := jsonArg(args)
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) && rv.IsNil() {
+		return "null"
+	}
+
+	switch n := v.(type) {
+	case json.Number:
+		return n.String()
+	case fmt.Stringer, error:
+		return jsonQuote(fmt.Sprint(v))
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return jsonQuote(rv.String())
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprint(v)
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(rv.Float()) || math.IsInf(rv.Float(), 0) {
+			return "null"
+		}
+		return fmt.Sprint(v)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return jsonQuote(fmt.Sprint(v))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonEscapeKey prints a value as an object key.
+func jsonEscapeKey(args ...interface{}) string {
+	v := jsonArg(args)
+	if v == nil {
+		return `""`
+	}
+	return jsonQuote(fmt.Sprint(v))
+}
+
+// jsonQuote returns s as a JSON string. Unlike json.Marshal it leaves <, > and & alone.
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,204 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_RenderJSONEscaping(t *testing.T) {
+	data := json.RawMessage(`{"CompanyID": 1, "CompanyName": "Say \"hi\"\\n\n<ok>", "Events": []}`)
+
+	tests := []struct {
+		name     string
+		template string
+		output   string
+	}{
+		{
+			name:     "String contents",
+			template: `{"name": "Company: {{ .CompanyName }}"}`,
+			output:   `{"name": "Company: Say \"hi\"\\n\n<ok>"}`,
+		},
+		{
+			name:     "Bare values",
+			template: `{"name": {{ .CompanyName }}, "id": {{ .CompanyID }}, "active": {{ .IsSingleEvent }}, "events": {{ .Events }}, "event": {{ .Event }}}`,
+			output:   `{"name": "Say \"hi\"\\n\n<ok>", "id": 1, "active": false, "events": [], "event": null}`,
+		},
+		{
+			name:     "Keys",
+			template: `{ {{ .CompanyName }} : {{ .CompanyID }}, {{ .CompanyID }}: true }`,
+			output:   `{ "Say \"hi\"\\n\n<ok>" : 1, "1": true }`,
+		},
+		{
+			name:     "JSON helpers",
+			template: `{"raw": {{ toJSON .CompanyName }}, "embedded": "{{ toJSON .CompanyName }}"}`,
+			output:   `{"raw": "Say \"hi\"\\n\n\u003cok\u003e", "embedded": "\"Say \\\"hi\\\"\\\\n\\n\\u003cok\\u003e\""}`,
+		},
+		{
+			name:     "Escaped quotes and constants",
+			template: `{"text": "\"{{ .CompanyID }}\"{{ "\\n" }}{{ .CompanyID }}", "id": {{ .CompanyID }}}`,
+			output:   `{"text": "\"1\"\n1", "id": 1}`,
+		},
+		{
+			name:     "Define called in a string",
+			template: `{{ define "name" }}{{ .CompanyName }}{{ end }}{"name": "{{ template "name" . }}"}`,
+			output:   `{"name": "Say \"hi\"\\n\n<ok>"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: tt.template, JSON: true, Data: data})
+			if resp.Error != "" {
+				t.Fatalf("Unexpected error: %s", resp.Error)
+			}
+			if resp.Output != tt.output {
+				t.Errorf("Expected output %s, got %s", tt.output, resp.Output)
+			}
+			if !json.Valid([]byte(resp.Output)) {
+				t.Errorf("Output is not valid JSON: %s", resp.Output)
+			}
+		})
+	}
+
+	// without the JSON flag, templates escape values themselves, whatever their name
+	unescaped := []struct {
+		name     string
+		template string
+		output   string
+	}{
+		{
+			name:     "Not requested",
+			template: `"{{ .CompanyName }}"`,
+			output:   "\"Say \"hi\"\\n\n<ok>\"",
+		},
+		{
+			name:     "JSON helper in a variable",
+			template: `{{ $j := toJSON .CompanyName }}{"name": {{ $j }}}`,
+			output:   `{"name": "Say \"hi\"\\n\n\u003cok\u003e"}`,
+		},
+		{
+			name:     "Quoted with printf",
+			template: `{"name": {{ .CompanyName | printf "%q" }}}`,
+			output:   `{"name": "Say \"hi\"\\n\n<ok>"}`,
+		},
+	}
+	for _, tt := range unescaped {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Name: "test.json.tmpl", Template: tt.template, Data: data})
+			if resp.Error != "" || resp.Output != tt.output {
+				t.Errorf("Expected output %s, got %s (error %q)", tt.output, resp.Output, resp.Error)
+			}
+		})
+	}
+}
+
+func Test_RenderJSONEscaping_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		error    string
+		line     int
+	}{
+		{
+			name:     "Branches end in different contexts",
+			template: "{\"a\": {{ if .IsSingleEvent }}\"yes{{ else }}\"no\"{{ end }}\"}",
+			error:    "{{if}} branches end in different JSON contexts",
+			line:     1,
+		},
+		{
+			name:     "Range body changes context",
+			template: "[\n{{ range .Events }}\"{{ .Type }},{{ end }}\"]",
+			error:    "{{range}} body ends in a different JSON context than it starts",
+			line:     2,
+		},
+		{
+			name:     "Define called in different contexts",
+			template: `{{ define "id" }}{{ .CompanyID }}{{ end }}{"a": {{ template "id" . }}, "b": "{{ template "id" . }}"}`,
+			error:    `template "id" is called in different JSON contexts`,
+			line:     1,
+		},
+		{
+			name:     "Action after backslash",
+			template: `{"a": "\{{ .CompanyName }}"}`,
+			error:    "action follows a backslash in a JSON string",
+			line:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Name: "test.json.tmpl", Template: tt.template, JSON: true, Data: TestingViewModels["alarm"]})
+			if resp.ErrorKind != ErrorKind_Parse || !strings.Contains(resp.Error, tt.error) {
+				t.Errorf("Expected parse error %q, got %s: %s", tt.error, resp.ErrorKind, resp.Error)
+			}
+			if resp.Line != tt.line || resp.TemplateName != "test.json.tmpl" {
+				t.Errorf("Expected error at test.json.tmpl:%d, got %s:%d", tt.line, resp.TemplateName, resp.Line)
+			}
+		})
+	}
+}
+
+func Test_RenderJSONEscaping_Sections(t *testing.T) {
+	data := json.RawMessage(`{"CompanyID": 1, "CompanyName": "Say \"hi\"", "Events": []}`)
+	helpers := `{{ define "name" }}{{ .CompanyName }}{{ end }}`
+
+	resp := Render(RenderRequest{Template: `{"name": "{{ .CompanyName }}"}`, JSON: true, Data: data})
+	if resp.Error != "" || resp.Output != `{"name": "Say \"hi\""}` {
+		t.Errorf("Expected the JSON flag to escape the template, got %s (error %q)", resp.Output, resp.Error)
+	}
+
+	resp = Render(RenderRequest{
+		Template: helpers,
+		Sections: map[string]string{Section_Body: `{"name": "{{ template "name" . }}"}`, Section_Query: `{{ .CompanyName }}`},
+		JSON:     true,
+		Data:     data,
+	})
+	if body := resp.Sections[Section_Body]; body.Error != "" || body.Output != `{"name": "Say \"hi\""}` {
+		t.Errorf("Expected sections of a JSON request to be escaped, got %s (error %q)", body.Output, body.Error)
+	}
+	if query := resp.Sections[Section_Query]; query.Error != "" || query.Output != `"Say \"hi\""` {
+		t.Errorf("Expected sections of a JSON request to be JSON values, got %s (error %q)", query.Output, query.Error)
+	}
+
+	resp = Render(RenderRequest{
+		Template:     helpers,
+		Sections:     map[string]string{Section_Subject: `Hello {{ .CompanyName }}`, Section_Body: `{"name": "{{ template "name" . }}"}`},
+		JSONSections: []string{Section_Body},
+		Data:         data,
+	})
+	if subject := resp.Sections[Section_Subject]; subject.Error != "" || subject.Output != `Hello Say "hi"` {
+		t.Errorf("Expected the subject to be left alone, got %s (error %q)", subject.Output, subject.Error)
+	}
+	if body := resp.Sections[Section_Body]; body.Error != "" || body.Output != `{"name": "Say \"hi\""}` {
+		t.Errorf("Expected the JSON body to be escaped, got %s (error %q)", body.Output, body.Error)
+	}
+
+	resp = Render(RenderRequest{
+		Template:     helpers,
+		Sections:     map[string]string{Section_Subject: `{{ template "name" . }}`, Section_Body: `"{{ template "name" . }}"`},
+		JSONSections: []string{Section_Body},
+		Data:         data,
+	})
+	if resp.ErrorKind != ErrorKind_Parse || !strings.Contains(resp.Error, `template "name" is called from both JSON and plain output`) {
+		t.Errorf("Expected a define block shared by JSON and plain sections to fail, got %s: %s", resp.ErrorKind, resp.Error)
+	}
+
+	resp = Render(RenderRequest{Sections: map[string]string{Section_Body: `{}`}, JSONSections: []string{"payload"}, Data: data})
+	if resp.ErrorKind != ErrorKind_Parse || !strings.Contains(resp.Error, `JSON section "payload" is not one of the sections`) {
+		t.Errorf("Expected an unknown JSON section to fail, got %s: %s", resp.ErrorKind, resp.Error)
+	}
+
+	resp = Render(RenderRequest{
+		Sections:     map[string]string{Section_Subject: `{`, Section_Body: `{`},
+		JSONSections: []string{Section_Body},
+		Strict:       true,
+		Data:         data,
+	})
+	if subject := resp.Sections[Section_Subject]; subject.Error != "" || subject.Output != "{" {
+		t.Errorf("Expected the plain subject not to be validated, got %s (error %q)", subject.Output, subject.Error)
+	}
+	if body := resp.Sections[Section_Body]; body.ErrorKind != ErrorKind_Output {
+		t.Errorf("Expected invalid JSON body to fail in strict mode, got %s: %s", body.ErrorKind, body.Error)
+	}
+}
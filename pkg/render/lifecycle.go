@@ -0,0 +1,97 @@
+package render
+
+import (
+	"strings"
+)
+
+// EventTransition classifies the state change an event notifies about.
+type EventTransition string
+
+const (
+	EventTransition_Opened       EventTransition = "opened"
+	EventTransition_Escalated    EventTransition = "escalated"
+	EventTransition_DeEscalated  EventTransition = "de-escalated"
+	EventTransition_Acknowledged EventTransition = "acknowledged"
+	EventTransition_Resolved     EventTransition = "resolved"
+	EventTransition_Updated      EventTransition = "updated"
+)
+
+// State names, compared case-insensitively, by the stage of the incident they
+// belong to. Open states not listed here, such as active, alarm or ackRequired,
+// count as updates of an open incident.
+var (
+	initialStates      = stateSet("", "n/a", "none", "new")
+	acknowledgedStates = stateSet("ack", "acked", "acknowledged")
+	closedStates       = stateSet("clear", "cleared", "archived", "inactive", "healthy", "resolved", "closed", "ended")
+)
+
+// severityRanks orders open states which are severity levels, to tell escalations from de-escalations.
+var severityRanks = func() map[string]int {
+	res := make(map[string]int)
+	for importance, name := range ImportanceNames {
+		if importance > ViewModelImportance_Healthy {
+			res[name] = int(importance)
+		}
+	}
+	return res
+}()
+
+func stateSet(states ...string) map[string]bool {
+	res := make(map[string]bool, len(states))
+	for _, state := range states {
+		res[strings.ToLower(state)] = true
+	}
+	return res
+}
+
+// Transition classifies the change from PreviousState to CurrentState: an inactive
+// event is resolved, a state following an initial or closed one opens an incident.
+func (event EventViewModel) Transition() EventTransition {
+	current, previous := strings.ToLower(event.CurrentState), strings.ToLower(event.PreviousState)
+	switch {
+	case !event.IsActive || closedStates[current]:
+		return EventTransition_Resolved
+	case acknowledgedStates[current]:
+		return EventTransition_Acknowledged
+	case initialStates[previous] || closedStates[previous]:
+		return EventTransition_Opened
+	}
+	if currentRank, ok := severityRanks[current]; ok {
+		if previousRank, ok := severityRanks[previous]; ok && currentRank != previousRank {
+			if currentRank > previousRank {
+				return EventTransition_Escalated
+			}
+			return EventTransition_DeEscalated
+		}
+	}
+	return EventTransition_Updated
+}
+
+// incidentActions maps transitions to the verb of each incident management platform:
+// PagerDuty event_action, Opsgenie alert action and ServiceNow event resolution_state.
+var incidentActions = map[string]map[EventTransition]string{
+	"pagerduty": {
+		EventTransition_Opened:       "trigger",
+		EventTransition_Escalated:    "trigger",
+		EventTransition_DeEscalated:  "trigger",
+		EventTransition_Updated:      "trigger",
+		EventTransition_Acknowledged: "acknowledge",
+		EventTransition_Resolved:     "resolve",
+	},
+	"opsgenie": {
+		EventTransition_Opened:       "create",
+		EventTransition_Escalated:    "escalate",
+		EventTransition_DeEscalated:  "addNote",
+		EventTransition_Updated:      "addNote",
+		EventTransition_Acknowledged: "acknowledge",
+		EventTransition_Resolved:     "close",
+	},
+	"servicenow": {
+		EventTransition_Opened:       "New",
+		EventTransition_Escalated:    "New",
+		EventTransition_DeEscalated:  "New",
+		EventTransition_Updated:      "New",
+		EventTransition_Acknowledged: "New",
+		EventTransition_Resolved:     "Closing",
+	},
+}
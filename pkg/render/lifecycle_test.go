@@ -0,0 +1,56 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_EventTransition(t *testing.T) {
+	tests := []struct {
+		previous string
+		current  string
+		inactive bool
+		expected EventTransition
+	}{
+		{previous: "new", current: "active", expected: EventTransition_Opened},
+		{previous: "n/a", current: "n/a", expected: EventTransition_Opened},
+		{previous: "cleared", current: "alarm", expected: EventTransition_Opened},
+		{previous: "warning", current: "critical", expected: EventTransition_Escalated},
+		{previous: "critical", current: "minor", expected: EventTransition_DeEscalated},
+		{previous: "ackRequired", current: "acknowledged", expected: EventTransition_Acknowledged},
+		{previous: "ackRequired", current: "archived", expected: EventTransition_Resolved},
+		{previous: "alarm", current: "alarm", inactive: true, expected: EventTransition_Resolved},
+		{previous: "ackRequired", current: "active", expected: EventTransition_Updated},
+		{previous: "critical", current: "Critical", expected: EventTransition_Updated},
+	}
+
+	for _, tt := range tests {
+		event := EventViewModel{PreviousState: tt.previous, CurrentState: tt.current, IsActive: !tt.inactive}
+		if transition := event.Transition(); transition != tt.expected {
+			t.Errorf("Expected %s → %s to be %s, got %s", tt.previous, tt.current, tt.expected, transition)
+		}
+	}
+}
+
+func Test_RenderIncidentAction(t *testing.T) {
+	tests := []struct {
+		template string
+		data     string
+		output   string
+		error    string
+	}{
+		{template: `{{ incidentAction "pagerduty" .Event }}`, data: "alarm", output: "trigger"},
+		{template: `{{ incidentAction "pagerduty" .Event }}`, data: "mitigation", output: "resolve"},
+		{template: `{{ .Event | incidentAction "opsgenie" }}`, data: "synthetics", output: "create"},
+		{template: `{{ incidentAction "servicenow" .Event }}`, data: "mitigation", output: "Closing"},
+		{template: `{{ if eq .Event.Transition "resolved" }}resolved{{ end }}`, data: "mitigation", output: "resolved"},
+		{template: `{{ incidentAction "jira" .Event }}`, data: "alarm", error: `unknown incident platform "jira", expected one of: opsgenie, pagerduty, servicenow`},
+	}
+
+	for _, tt := range tests {
+		resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels[tt.data]})
+		if resp.Output != tt.output || !strings.Contains(resp.Error, tt.error) || (tt.error == "") != (resp.Error == "") {
+			t.Errorf("Expected %q (error %q) for %s, got %q (error %q)", tt.output, tt.error, tt.template, resp.Output, resp.Error)
+		}
+	}
+}
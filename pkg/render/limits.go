@@ -0,0 +1,181 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// Limit kinds reported by LimitError.
+const (
+	Limit_Context         = "context"
+	Limit_OutputBytes     = "output_bytes"
+	Limit_RangeIterations = "range_iterations"
+)
+
+// RenderOptions restricts resources used by a single render. Zero values mean no limit.
+type RenderOptions struct {
+	// MaxOutputBytes caps the total size of the rendered output (all sections together).
+	MaxOutputBytes int
+	// MaxRangeIterations caps the total number of items iterated by range actions.
+	MaxRangeIterations int
+}
+
+// LimitError is returned when rendering is stopped by a limit from RenderOptions
+// or by the context being canceled or reaching its deadline.
+type LimitError struct {
+	Limit string
+	Max   int
+	Err   error
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case Limit_OutputBytes:
+		return fmt.Sprintf("render limit exceeded: output larger than %d bytes", e.Max)
+	case Limit_RangeIterations:
+		return fmt.Sprintf("render limit exceeded: more than %d range iterations", e.Max)
+	default:
+		return fmt.Sprintf("render stopped: %s", e.Err)
+	}
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// execState tracks limits and warnings of one execution, shared by all its sections.
+type execState struct {
+	ctx        context.Context
+	opts       RenderOptions
+	written    int
+	iterations int
+	ticks      int
+	stopped    error
+
+	strict   bool
+	sites    []warningSite
+	warnings []Warning
+	warned   map[warningKey]bool
+}
+
+func newExecState(ctx context.Context, opts RenderOptions) *execState {
+	return &execState{ctx: ctx, opts: opts}
+}
+
+// checkContext reports cancellation. The deadline is compared explicitly as well,
+// because under js/wasm timers cannot fire while a render occupies the only thread.
+func (s *execState) checkContext() error {
+	if err := s.ctx.Err(); err != nil {
+		return &LimitError{Limit: Limit_Context, Err: err}
+	}
+	if deadline, ok := s.ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return &LimitError{Limit: Limit_Context, Err: context.DeadlineExceeded}
+	}
+	return nil
+}
+
+// guardRange is bound as rangeGuardFunc and sees every value about to be
+// ranged over, with the number of variables the range declares.
+func (s *execState) guardRange(vars int, v interface{}) (interface{}, error) {
+	if err := s.checkContext(); err != nil {
+		return nil, err
+	}
+	if s.opts.MaxRangeIterations > 0 {
+		s.iterations += rangeLength(v)
+		if s.iterations > s.opts.MaxRangeIterations {
+			return nil, &LimitError{Limit: Limit_RangeIterations, Max: s.opts.MaxRangeIterations}
+		}
+	}
+	if vars <= 1 {
+		if seq, ok := s.countTo(v); ok {
+			return seq, nil
+		}
+	}
+	return v, nil
+}
+
+// tickInterval is the number of iterations between context checks of countTo.
+const tickInterval = 256
+
+// countTo replaces an integer to range over with an iterator yielding the
+// same values, which stops early when the context is done: unlike ranges
+// over data, such ranges may run long without writing any output. The
+// error is kept in stopped, execution fails with it once the range ends.
+func (s *execState) countTo(v interface{}) (interface{}, bool) {
+	n := reflect.ValueOf(v)
+	switch n.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return nil, false
+	}
+
+	yieldType := reflect.FuncOf([]reflect.Type{n.Type()}, []reflect.Type{reflect.TypeFor[bool]()}, false)
+	seqType := reflect.FuncOf([]reflect.Type{yieldType}, nil, false)
+	seq := reflect.MakeFunc(seqType, func(args []reflect.Value) []reflect.Value {
+		for i := range n.Seq() {
+			if s.ticks++; s.ticks%tickInterval == 0 {
+				if err := s.checkContext(); err != nil {
+					s.stopped = err
+					break
+				}
+			}
+			if !args[0].Call([]reflect.Value{i})[0].Bool() {
+				break
+			}
+		}
+		return nil
+	})
+	return seq.Interface(), true
+}
+
+// writer wraps w so that every write checks the context and the output size.
+func (s *execState) writer(w io.Writer) io.Writer {
+	return &limitedWriter{w: w, state: s}
+}
+
+type limitedWriter struct {
+	w     io.Writer
+	state *execState
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	s := lw.state
+	if err := s.checkContext(); err != nil {
+		return 0, err
+	}
+	if s.opts.MaxOutputBytes > 0 && s.written+len(p) > s.opts.MaxOutputBytes {
+		return 0, &LimitError{Limit: Limit_OutputBytes, Max: s.opts.MaxOutputBytes}
+	}
+	n, err := lw.w.Write(p)
+	s.written += n
+	return n, err
+}
+
+// rangeLength returns the number of iterations a range over v performs.
+func rangeLength(v interface{}) int {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return 0
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Chan:
+		return rv.Len()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(max(rv.Int(), 0))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(min(rv.Uint(), uint64(^uint(0)>>1)))
+	case reflect.Invalid:
+		return 0
+	default:
+		// iterator functions cannot be measured upfront
+		return 1
+	}
+}
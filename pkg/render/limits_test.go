@@ -0,0 +1,123 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_RenderContext_Limits(t *testing.T) {
+	nested := `{{ range .Events }}{{ range $.Events }}{{ range $.Events }}x{{ end }}{{ end }}{{ end }}`
+
+	tests := []struct {
+		name      string
+		template  string
+		opts      RenderOptions
+		wantLimit string
+	}{
+		{
+			name:     "Within limits",
+			template: nested,
+			opts:     RenderOptions{MaxOutputBytes: 1000, MaxRangeIterations: 1000},
+		},
+		{
+			name:      "Output bytes",
+			template:  `{{ range .Events }}{{ $.CompanyName }}{{ end }}`,
+			opts:      RenderOptions{MaxOutputBytes: 20},
+			wantLimit: Limit_OutputBytes,
+		},
+		{
+			name:      "Range iterations",
+			template:  nested,
+			opts:      RenderOptions{MaxRangeIterations: 10},
+			wantLimit: Limit_RangeIterations,
+		},
+		{
+			name:      "Range over integer",
+			template:  `{{ range 100000 }}{{ end }}`,
+			opts:      RenderOptions{MaxRangeIterations: 1000},
+			wantLimit: Limit_RangeIterations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := RenderContext(context.Background(), RenderRequest{Template: tt.template, Data: TestingViewModels["digest"]}, tt.opts)
+
+			var limitErr *LimitError
+			if tt.wantLimit == "" {
+				if resp.Error != "" {
+					t.Fatalf("Unexpected error: %s", resp.Error)
+				}
+				return
+			}
+			if !errors.As(resp.Err(), &limitErr) {
+				t.Fatalf("Expected LimitError, got %v", resp.Err())
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Expected limit %s, got %s", tt.wantLimit, limitErr.Limit)
+			}
+			if strings.Contains(resp.Error, rangeGuardFunc) {
+				t.Errorf("Error should not leak internal function names: %s", resp.Error)
+			}
+		})
+	}
+}
+
+func Test_RenderContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp := RenderContext(ctx, RenderRequest{Template: "{{ .CompanyName }}", Data: TestingViewModels["alarm"]}, RenderOptions{})
+	if !errors.Is(resp.Err(), context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", resp.Err())
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	resp = NewRenderer(0).RenderContext(ctx, RenderRequest{Template: "{{ .CompanyName }}", Data: TestingViewModels["alarm"]}, RenderOptions{})
+	if !errors.Is(resp.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", resp.Err())
+	}
+}
+
+func Test_RenderContext_EmptyLoop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	resp := RenderContext(ctx, RenderRequest{Template: "{{ range 300000000 }}{{ end }}", Data: TestingViewModels["alarm"]}, RenderOptions{})
+	if !errors.Is(resp.Err(), context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", resp.Err())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the loop to stop soon after the deadline, took %s", elapsed)
+	}
+	if resp.ErrorKind != ErrorKind_Limit || resp.Output != "" {
+		t.Errorf("Expected a limit error and no output, got %q (%s)", resp.Output, resp.ErrorKind)
+	}
+}
+
+func Test_RenderContext_IntegerRanges(t *testing.T) {
+	tests := []struct {
+		template string
+		output   string
+	}{
+		{template: `{{ range $i := 3 }}{{ $i }}{{ end }}`, output: "012"},
+		{template: `{{ range 0 }}x{{ else }}none{{ end }}`, output: "none"},
+		{template: `{{ range $i := 1000 }}{{ if eq $i 2 }}{{ break }}{{ end }}{{ $i }}{{ end }}`, output: "01"},
+		{template: `{{ range $i := 600 }}{{ if lt $i 598 }}{{ continue }}{{ end }}{{ $i }};{{ end }}`, output: "598;599;"},
+		{template: `{{ range $i := .CompanyID }}{{ if ge $i 2 }}{{ break }}{{ end }}{{ printf "%T" $i }};{{ end }}`, output: "int;int;"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, tt := range tests {
+		resp := RenderContext(ctx, RenderRequest{Template: tt.template, Data: TestingViewModels["alarm"]}, RenderOptions{})
+		if resp.Error != "" || resp.Output != tt.output {
+			t.Errorf("Expected %q for %s, got %q (error %q)", tt.output, tt.template, resp.Output, resp.Error)
+		}
+	}
+}
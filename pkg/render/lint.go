@@ -0,0 +1,490 @@
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template/parse"
+
+	"github.com/kentik/custom-notification-templates/pkg/schemas"
+)
+
+// Lint issue severities.
+const (
+	Severity_Error   = "error"
+	Severity_Warning = "warning"
+)
+
+// LintIssue is a problem found by Lint, located in the template source.
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Range
+}
+
+// builtinFuncArity lists text/template builtins with their minimum number of arguments.
+var builtinFuncArity = map[string]int{
+	"and": 1, "or": 1, "not": 1, "len": 1, "index": 1, "slice": 1, "call": 1,
+	"print": 0, "printf": 1, "println": 0, "html": 0, "js": 0, "urlquery": 0,
+	"eq": 2, "ne": 2, "lt": 2, "le": 2, "gt": 2, "ge": 2,
+}
+
+var builtinFuncTypes = map[string]reflect.Type{
+	"not": reflect.TypeOf(true), "len": reflect.TypeOf(0),
+	"print": reflect.TypeOf(""), "printf": reflect.TypeOf(""), "println": reflect.TypeOf(""),
+	"html": reflect.TypeOf(""), "js": reflect.TypeOf(""), "urlquery": reflect.TypeOf(""),
+	"eq": reflect.TypeOf(true), "ne": reflect.TypeOf(true), "lt": reflect.TypeOf(true),
+	"le": reflect.TypeOf(true), "gt": reflect.TypeOf(true), "ge": reflect.TypeOf(true),
+}
+
+// detailNameMethods are EventViewModelDetails methods taking detail names as arguments.
+var detailNameMethods = map[string]bool{"Get": true, "GetValue": true, "Has": true, "WithNames": true}
+
+var (
+	rootType    = reflect.TypeOf(&NotificationViewModel{})
+	detailsType = reflect.TypeOf(EventViewModelDetails{})
+)
+
+// documentedDetails maps detail names to their documentation.
+var documentedDetails = sync.OnceValue(func() map[string]schemas.Detail {
+	res := make(map[string]schemas.Detail)
+	for _, detail := range schemas.Details() {
+		if detail.Name != "" {
+			res[detail.Name] = detail
+		}
+	}
+	return res
+})
+
+// Lint statically checks a template without rendering it: field and method
+// chains are resolved against the view model types, function calls against
+// TextTemplateFuncMap including their arity, and detail names passed to
+// GetValue, Get, Has and WithNames against the documented details.
+func Lint(text string) []LintIssue {
+	l := &linter{
+		text:    text,
+		trees:   make(map[string]*parse.Tree),
+		visited: make(map[string]bool),
+	}
+
+	tree := parse.New("template")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", l.trees); err != nil {
+		parseErr := newParseError("template", text, err)
+		return []LintIssue{{Severity: Severity_Error, Message: err.Error(), Range: parseErr.Range}}
+	}
+	if _, ok := l.trees["template"]; !ok {
+		l.trees["template"] = tree
+	}
+
+	l.walk(l.trees["template"].Root, rootType, nil)
+	for _, token := range findTokens(text) {
+		l.issues = append(l.issues, LintIssue{Severity: Severity_Warning, Message: tokenMessage(token.kind), Range: rangeOf(text, token.start, token.end)})
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i].Range, l.issues[j].Range
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartColumn < b.StartColumn
+	})
+	return l.issues
+}
+
+type lintVar struct {
+	name string
+	typ  reflect.Type
+}
+
+// chainUse tells how a field chain resolved when a template was analyzed.
+type chainUse struct {
+	event   int  // index of the NotificationViewModel.Event method among the chain names, -1 if none
+	details bool // the chain ends in a detail lookup on EventViewModelDetails
+	mixed   bool // visits disagree, e.g. a define block executed with different data
+}
+
+// analyzeChains type-checks the named templates of a parsed set, executed
+// with the view model, and reports how each field chain resolved.
+func analyzeChains(trees map[string]*parse.Tree, names []string) map[parse.Node]chainUse {
+	l := &linter{
+		trees:   trees,
+		visited: make(map[string]bool),
+		uses:    make(map[parse.Node]chainUse),
+	}
+	for _, name := range names {
+		if tree, ok := trees[name]; ok {
+			l.walk(tree.Root, rootType, nil)
+		}
+	}
+	return l.uses
+}
+
+type linter struct {
+	text    string
+	trees   map[string]*parse.Tree
+	issues  []LintIssue
+	visited map[string]bool
+
+	// uses is only set when analyzing chains, issues are not collected then
+	uses map[parse.Node]chainUse
+}
+
+func (l *linter) record(node parse.Node, use chainUse) {
+	if prev, ok := l.uses[node]; ok && prev != use {
+		use.mixed = true
+	}
+	l.uses[node] = use
+}
+
+func (l *linter) report(severity string, start, length int, format string, args ...interface{}) {
+	if l.uses != nil {
+		return
+	}
+	l.issues = append(l.issues, LintIssue{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Range:    rangeOf(l.text, start, start+length),
+	})
+}
+
+// walk checks a list of nodes executed with dot of type dot. A nil type means unknown.
+func (l *linter) walk(node parse.Node, dot reflect.Type, vars []lintVar) []lintVar {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return vars
+		}
+		for _, child := range n.Nodes {
+			vars = l.walk(child, dot, vars)
+		}
+	case *parse.ActionNode:
+		vars = l.pipe(n.Pipe, dot, vars)
+	case *parse.IfNode:
+		inner := l.pipe(n.Pipe, dot, vars)
+		l.walk(n.List, dot, inner)
+		l.walk(n.ElseList, dot, inner)
+	case *parse.WithNode:
+		inner := l.pipe(n.Pipe, dot, vars)
+		l.walk(n.List, l.pipeType(n.Pipe, dot, vars), inner)
+		l.walk(n.ElseList, dot, inner)
+	case *parse.RangeNode:
+		typ := l.pipeType(n.Pipe, dot, vars)
+		key, elem := rangeTypes(typ)
+		inner := append([]lintVar{}, vars...)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner = append(inner, lintVar{n.Pipe.Decl[0].Ident[0], elem})
+		case 2:
+			inner = append(inner, lintVar{n.Pipe.Decl[0].Ident[0], key}, lintVar{n.Pipe.Decl[1].Ident[0], elem})
+		}
+		l.pipeCmds(n.Pipe, dot, vars)
+		l.walk(n.List, elem, inner)
+		l.walk(n.ElseList, dot, vars)
+	case *parse.TemplateNode:
+		var typ reflect.Type
+		if n.Pipe != nil {
+			typ = l.pipeCmds(n.Pipe, dot, vars)
+		}
+		key := n.Name + "\x00" + fmt.Sprint(typ)
+		if tree, ok := l.trees[n.Name]; ok && !l.visited[key] {
+			l.visited[key] = true
+			l.walk(tree.Root, typ, []lintVar{{"$", typ}})
+		}
+	}
+	return vars
+}
+
+// pipe checks a pipeline and returns the variables in scope after it.
+func (l *linter) pipe(pipe *parse.PipeNode, dot reflect.Type, vars []lintVar) []lintVar {
+	typ := l.pipeCmds(pipe, dot, vars)
+	if pipe.IsAssign {
+		return vars
+	}
+	for _, decl := range pipe.Decl {
+		vars = append(vars, lintVar{decl.Ident[0], typ})
+	}
+	return vars
+}
+
+func (l *linter) pipeType(pipe *parse.PipeNode, dot reflect.Type, vars []lintVar) reflect.Type {
+	saved := len(l.issues)
+	typ := l.pipeCmds(pipe, dot, vars)
+	l.issues = l.issues[:saved]
+	return typ
+}
+
+func (l *linter) pipeCmds(pipe *parse.PipeNode, dot reflect.Type, vars []lintVar) reflect.Type {
+	var typ reflect.Type
+	for i, cmd := range pipe.Cmds {
+		typ = l.command(cmd, dot, vars, i > 0)
+	}
+	return typ
+}
+
+// command checks a single command; final tells whether the previous command's result is piped into it.
+func (l *linter) command(cmd *parse.CommandNode, dot reflect.Type, vars []lintVar, final bool) reflect.Type {
+	args := cmd.Args[1:]
+	for _, arg := range args {
+		l.arg(arg, dot, vars)
+	}
+
+	switch n := cmd.Args[0].(type) {
+	case *parse.FieldNode:
+		return l.chain(n, fieldStart(n), dot, n.Ident, args, final)
+	case *parse.VariableNode:
+		return l.chain(n, variableStart(n)+len(n.Ident[0]), lookupVar(vars, n.Ident[0]), n.Ident[1:], args, final)
+	case *parse.ChainNode:
+		return l.chain(n, int(n.Pos), l.arg(n.Node, dot, vars), n.Field, args, final)
+	case *parse.IdentifierNode:
+		return l.function(n, args, final)
+	default:
+		return l.arg(n, dot, vars)
+	}
+}
+
+// arg returns the type of a command argument, checking nested pipelines.
+func (l *linter) arg(node parse.Node, dot reflect.Type, vars []lintVar) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return l.chain(n, fieldStart(n), dot, n.Ident, nil, false)
+	case *parse.VariableNode:
+		return l.chain(n, variableStart(n)+len(n.Ident[0]), lookupVar(vars, n.Ident[0]), n.Ident[1:], nil, false)
+	case *parse.ChainNode:
+		return l.chain(n, int(n.Pos), l.arg(n.Node, dot, vars), n.Field, nil, false)
+	case *parse.PipeNode:
+		return l.pipeCmds(n, dot, vars)
+	case *parse.IdentifierNode:
+		return l.function(n, nil, false)
+	case *parse.StringNode:
+		return reflect.TypeOf("")
+	case *parse.BoolNode:
+		return reflect.TypeOf(true)
+	case *parse.NumberNode:
+		if n.IsInt {
+			return reflect.TypeOf(0)
+		}
+		return reflect.TypeOf(0.0)
+	}
+	return nil
+}
+
+func (l *linter) function(n *parse.IdentifierNode, args []parse.Node, final bool) reflect.Type {
+	count := len(args)
+	if final {
+		count++
+	}
+
+	if minArgs, ok := builtinFuncArity[n.Ident]; ok {
+		if count < minArgs {
+			l.report(Severity_Error, int(n.Pos), len(n.Ident), "wrong number of args for %s: want at least %d got %d", n.Ident, minArgs, count)
+		}
+		return builtinFuncTypes[n.Ident]
+	}
+
+	fn, ok := TextTemplateFuncMap[n.Ident]
+	if !ok {
+		l.report(Severity_Error, int(n.Pos), len(n.Ident), "function %q not defined", n.Ident)
+		return nil
+	}
+
+	typ := reflect.TypeOf(fn)
+	l.checkArity(int(n.Pos), len(n.Ident), n.Ident, typ.NumIn(), typ.IsVariadic(), count)
+	return resultType(typ)
+}
+
+// chain resolves field and method names one by one starting from typ; start
+// is the source offset of the first name's leading dot.
+func (l *linter) chain(node parse.Node, start int, typ reflect.Type, names []string, args []parse.Node, final bool) reflect.Type {
+	use := chainUse{event: -1}
+	if l.uses != nil {
+		defer func() { l.record(node, use) }()
+	}
+
+	for i, name := range names {
+		last := i == len(names)-1
+		count := 0
+		if last {
+			count = len(args)
+			if final {
+				count++
+			}
+		}
+		at := start
+		for _, prev := range names[:i] {
+			at += len(prev) + 1
+		}
+
+		if typ == nil || typ.Kind() == reflect.Interface {
+			return nil
+		}
+
+		if method, ok := findMethod(typ, name); ok {
+			l.checkArity(at, len(name)+1, name, method.Type.NumIn()-1, method.Type.IsVariadic(), count)
+			if last && detailNameMethods[name] && derefType(typ) == detailsType {
+				l.detailNames(args)
+				use.details = typ == detailsType && (name == "WithNames" || count == 1)
+			}
+			if name == "Event" && typ == rootType && use.event < 0 {
+				use.event = i
+			}
+			typ = resultType(method.Type)
+			continue
+		}
+
+		base := derefType(typ)
+		switch base.Kind() {
+		case reflect.Struct:
+			field, ok := base.FieldByName(name)
+			if !ok || !field.IsExported() {
+				l.report(Severity_Error, at, len(name)+1, "can't evaluate field %s in type %s", name, getTypeName(typ))
+				return nil
+			}
+			typ = field.Type
+		case reflect.Map:
+			typ = base.Elem()
+		default:
+			l.report(Severity_Error, at, len(name)+1, "can't evaluate field %s in type %s", name, getTypeName(typ))
+			return nil
+		}
+		if last && count > 0 {
+			l.report(Severity_Error, at, len(name)+1, "%s has arguments but cannot be invoked as function", name)
+		}
+	}
+	return typ
+}
+
+func (l *linter) checkArity(start, length int, name string, numIn int, variadic bool, count int) {
+	switch {
+	case variadic && count < numIn-1:
+		l.report(Severity_Error, start, length, "wrong number of args for %s: want at least %d got %d", name, numIn-1, count)
+	case !variadic && count != numIn:
+		l.report(Severity_Error, start, length, "wrong number of args for %s: want %d got %d", name, numIn, count)
+	}
+}
+
+// detailNames checks literal detail names against the documented details.
+func (l *linter) detailNames(args []parse.Node) {
+	for _, arg := range args {
+		str, ok := arg.(*parse.StringNode)
+		if !ok {
+			continue
+		}
+		detail, known := documentedDetails()[str.Text]
+		switch {
+		case !known:
+			msg := fmt.Sprintf("unknown detail name %q", str.Text)
+			if suggestion := l.closestDetail(str.Text); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			l.report(Severity_Warning, int(str.Pos), len(str.Quoted), "%s", msg)
+		case detail.Deprecated:
+			l.report(Severity_Warning, int(str.Pos), len(str.Quoted), "detail %q is deprecated", str.Text)
+		}
+	}
+}
+
+// closestDetail suggests a documented detail name within a small edit distance.
+func (l *linter) closestDetail(name string) string {
+	best, bestDistance := "", 3
+	for candidate := range documentedDetails() {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDistance || d == bestDistance && best != "" && candidate < best {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// fieldStart returns the offset of a field chain. The parser positions
+// multi-name chains at their second name.
+func fieldStart(n *parse.FieldNode) int {
+	if len(n.Ident) > 1 {
+		return int(n.Pos) - len(n.Ident[0]) - 1
+	}
+	return int(n.Pos)
+}
+
+// variableStart returns the offset of a variable, see fieldStart.
+func variableStart(n *parse.VariableNode) int {
+	if len(n.Ident) > 1 {
+		return int(n.Pos) - len(n.Ident[0])
+	}
+	return int(n.Pos)
+}
+
+func lookupVar(vars []lintVar, name string) reflect.Type {
+	if name == "$" && (len(vars) == 0 || vars[0].name != "$") {
+		return rootType
+	}
+	for i := len(vars) - 1; i >= 0; i-- {
+		if vars[i].name == name {
+			return vars[i].typ
+		}
+	}
+	return nil
+}
+
+// findMethod looks up a method on the type or its pointer, as text/template does for addressable values.
+func findMethod(typ reflect.Type, name string) (reflect.Method, bool) {
+	if method, ok := typ.MethodByName(name); ok {
+		return method, true
+	}
+	if typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		return reflect.PointerTo(typ).MethodByName(name)
+	}
+	return reflect.Method{}, false
+}
+
+func derefType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+func resultType(fn reflect.Type) reflect.Type {
+	if fn.NumOut() == 0 {
+		return nil
+	}
+	return fn.Out(0)
+}
+
+// rangeTypes returns the key and element types produced by ranging over typ.
+func rangeTypes(typ reflect.Type) (reflect.Type, reflect.Type) {
+	if typ == nil {
+		return nil, nil
+	}
+	switch base := derefType(typ); base.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0), base.Elem()
+	case reflect.Map:
+		return base.Key(), base.Elem()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return base, base
+	}
+	return nil, nil
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_Lint(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []LintIssue
+	}{
+		{
+			name:     "Valid template",
+			template: `{{ $.CompanyName }}{{ range .Events }}{{ .Details.GetValue "AlarmSeverity" }}{{ end }}`,
+		},
+		{
+			name:     "Misspelled detail name",
+			template: `{{ .Event.Details.GetValue "AlarmSevrity" }}`,
+			want: []LintIssue{
+				{Severity: Severity_Warning, Message: `unknown detail name "AlarmSevrity", did you mean "AlarmSeverity"?`, Range: Range{StartLine: 1, StartColumn: 28, EndLine: 1, EndColumn: 42}},
+			},
+		},
+		{
+			name:     "Deprecated detail name",
+			template: `{{ with .Event }}{{ .Details.Has "AlarmSeverityLabel" }}{{ end }}`,
+			want: []LintIssue{
+				{Severity: Severity_Warning, Message: `detail "AlarmSeverityLabel" is deprecated`, Range: Range{StartLine: 1, StartColumn: 34, EndLine: 1, EndColumn: 54}},
+			},
+		},
+		{
+			name:     "Missing field in range",
+			template: "{{ range .Events }}\n  {{ .Titl }}\n{{ end }}",
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: "can't evaluate field Titl in type *EventViewModel", Range: Range{StartLine: 2, StartColumn: 6, EndLine: 2, EndColumn: 11}},
+			},
+		},
+		{
+			name:     "Missing field on variable",
+			template: `{{ $e := .Event }}{{ $e.Foo.Bar }}`,
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: "can't evaluate field Foo in type *EventViewModel", Range: Range{StartLine: 1, StartColumn: 24, EndLine: 1, EndColumn: 28}},
+			},
+		},
+		{
+			name:     "Functions",
+			template: `{{ .CompanyName | toUpper }}{{ toUpper }}{{ nope 1 }}`,
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: "wrong number of args for toUpper: want 1 got 0", Range: Range{StartLine: 1, StartColumn: 32, EndLine: 1, EndColumn: 39}},
+				{Severity: Severity_Error, Message: `function "nope" not defined`, Range: Range{StartLine: 1, StartColumn: 45, EndLine: 1, EndColumn: 49}},
+			},
+		},
+		{
+			name:     "Inlined token",
+			template: `{"routing_key": "0123456789abcdef0123456789abcdef"}`,
+			want: []LintIssue{
+				{Severity: Severity_Warning, Message: "template inlines what looks like a PagerDuty integration key, pass it as a secret param instead", Range: Range{StartLine: 1, StartColumn: 18, EndLine: 1, EndColumn: 50}},
+			},
+		},
+		{
+			name:     "Method arity",
+			template: `{{ .Event.Details.WithTag }}`,
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: "wrong number of args for WithTag: want 1 got 0", Range: Range{StartLine: 1, StartColumn: 18, EndLine: 1, EndColumn: 26}},
+			},
+		},
+		{
+			name:     "Internal function",
+			template: `{{ _render_event 0 . "x" }}`,
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: `function "_render_event" not defined`, Range: Range{StartLine: 1, StartColumn: 4, EndLine: 1, EndColumn: 17}},
+			},
+		},
+		{
+			name:     "Comparison arity",
+			template: `{{ eq .CompanyName }}{{ .CompanyName | eq "x" }}`,
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: "wrong number of args for eq: want at least 2 got 1", Range: Range{StartLine: 1, StartColumn: 4, EndLine: 1, EndColumn: 6}},
+			},
+		},
+		{
+			name:     "Defined template",
+			template: `{{ define "x" }}{{ .Bad }}{{ end }}{{ template "x" . }}`,
+			want: []LintIssue{
+				{Severity: Severity_Error, Message: "can't evaluate field Bad in type *NotificationViewModel", Range: Range{StartLine: 1, StartColumn: 20, EndLine: 1, EndColumn: 24}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Lint(tt.template)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("Expected %d issues, got %+v", len(tt.want), issues)
+			}
+			for i := range issues {
+				if issues[i] != tt.want[i] {
+					t.Errorf("Expected %+v, got %+v", tt.want[i], issues[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_Lint_AllExamples(t *testing.T) {
+	templates, err := templateFiles("../../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range templates {
+		content, err := os.ReadFile(entry.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, issue := range Lint(string(content)) {
+			if issue.Severity == Severity_Error {
+				t.Errorf("%s:%d:%d: %s", entry.Name, issue.StartLine, issue.StartColumn, issue.Message)
+			}
+		}
+	}
+}
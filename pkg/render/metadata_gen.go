@@ -6,29 +6,40 @@ package render
 // methodDescriptions maps TypeName.MethodName to their documentation.
 // This is auto-generated from doc comments on methods in types.go.
 var methodDescriptions = map[string]string{
+	"DataError.Error":                                 "",
+	"DataError.Unwrap":                                "",
 	"EventViewModel.AddDetail":                        "AddDetail adds a detail to the event's Details collection.",
+	"EventViewModel.DedupKey":                         "DedupKey returns a stable identity of the event, the same for all of its state changes: values of DedupKeyDetails joined by dots, leaving out missing ones, or an empty string for other event types.",
 	"EventViewModel.IsAlarm":                          "IsAlarm returns true if event type is alarm.",
 	"EventViewModel.IsCustomInsight":                  "IsCustomInsight returns true if event type is custom-insight.",
 	"EventViewModel.IsInsight":                        "IsInsight returns true if event type is insight or custom-insight.",
 	"EventViewModel.IsMitigation":                     "IsMitigation returns true if event type is mitigation.",
 	"EventViewModel.IsSynthetic":                      "IsSynthetic returns true if event type is synthetic.",
+	"EventViewModel.Transition":                       "Transition classifies the change from PreviousState to CurrentState: an inactive event is resolved, a state following an initial or closed one opens an incident.",
 	"EventViewModel.UnmarshalJSON":                    "",
 	"EventViewModelDetail.LabelOrName":                "LabelOrName returns Label if set, otherwise returns Name.",
-	"EventViewModelDetail.UnmarshalJSON":              "",
+	"EventViewModelDetail.Unit":                       "Unit returns the unit of a metric detail value, one of Unit_*, or an empty string if not known.",
+	"EventViewModelDetail.UnmarshalJSON":              "UnmarshalJSON keeps numbers in Value as json.Number, so that large IDs and counters are printed exactly and not in exponent form.",
 	"EventViewModelDetails.General":                   "General returns details with an empty tag.",
 	"EventViewModelDetails.Get":                       "Get retrieves a detail by name.",
 	"EventViewModelDetails.GetValue":                  "GetValue retrieves a value by name.",
 	"EventViewModelDetails.Has":                       "Has checks if a detail with the given name exists.",
 	"EventViewModelDetails.HasTag":                    "HasTag checks if any detail has the specified tag.",
 	"EventViewModelDetails.Names":                     "Names returns all detail names.",
-	"EventViewModelDetails.PrettifiedMetrics":         "PrettifiedMetrics returns metric details with formatted values.",
+	"EventViewModelDetails.PrettifiedMetrics":         "PrettifiedMetrics returns metric details with formatted values, labelled with their unit if known (see MetricUnits).",
 	"EventViewModelDetails.ToMap":                     "ToMap converts details to a name-to-value map.",
 	"EventViewModelDetails.Values":                    "Values returns all detail values.",
 	"EventViewModelDetails.WithNames":                 "WithNames filters details by the given names.",
 	"EventViewModelDetails.WithTag":                   "WithTag filters details by the specified tag.",
+	"ExecError.Error":                                 "",
+	"ExecError.Unwrap":                                "",
+	"LimitError.Error":                                "",
+	"LimitError.Unwrap":                               "",
+	"NotificationViewConfig.UnmarshalJSON":            "",
 	"NotificationViewModel.ActiveCount":               "ActiveCount returns the count of currently active events.",
 	"NotificationViewModel.BasePortalURL":             "BasePortalURL returns the portal base URL (without path).",
 	"NotificationViewModel.Copyrights":                "Copyrights returns the copyright string with current year.",
+	"NotificationViewModel.Digest":                    "Digest returns the whole notification when each of its events is rendered separately (fan-out), otherwise the notification itself.",
 	"NotificationViewModel.Event":                     "Event returns the first event or nil if empty.",
 	"NotificationViewModel.Events":                    "Events returns all events as a slice.",
 	"NotificationViewModel.Headline":                  "Headline returns the generated headline text.",
@@ -48,6 +59,24 @@ var methodDescriptions = map[string]string{
 	"NotificationViewModel.Summary":                   "Summary returns the generated summary text.",
 	"NotificationViewModel.SyntheticsDashboardURL":    "SyntheticsDashboardURL returns the synthetics dashboard URL.",
 	"NotificationViewModel.UnmarshalJSON":             "",
+	"OutputError.Error":                               "",
+	"OutputError.Unwrap":                              "",
+	"ParamError.Error":                                "",
+	"ParamError.Unwrap":                               "",
+	"ParseError.Error":                                "",
+	"ParseError.Unwrap":                               "",
+	"Range.IsZero":                                    "IsZero reports whether the range is unknown.",
+	"RenderResponse.Err":                              "Err returns the error behind the Error message, or nil on success.",
+	"Renderer.Len":                                    "Len returns the number of compiled templates currently cached.",
+	"Renderer.Render":                                 "Render works like the package level Render, reusing a cached compiled template when possible.",
+	"Renderer.RenderContext":                          "RenderContext works like the package level RenderContext, reusing a cached compiled template when possible.",
+	"formattedNumber.Format":                          "",
+	"limitedWriter.Write":                             "",
+	"restoredError.Error":                             "",
+	"restoredError.Unwrap":                            "",
+	"siteError.Error":                                 "",
+	"siteError.Unwrap":                                "",
+	"skipError.Error":                                 "",
 }
 
 // functionMetadata contains metadata for all template functions.
@@ -59,12 +88,36 @@ var functionMetadata = []*SchemaFunction{
 		Description: "compactJSON compacts a JSON string by removing whitespace.",
 		Category:    "conversion",
 	},
+	{
+		Name:        "dedupKey",
+		Signature:   "(event *EventViewModel, length ...int) (string, error)",
+		Description: "dedupKey returns the DedupKey of the event, hashed to the given number of hex digits (at most 64) if passed.",
+		Category:    "utility",
+	},
 	{
 		Name:        "explodeJSONKeys",
 		Signature:   "(s string) string",
 		Description: "explodeJSONKeys extracts object key-values without braces.",
 		Category:    "conversion",
 	},
+	{
+		Name:        "formatUnit",
+		Signature:   "(value interface{}, unit string, system ...string) (string, error)",
+		Description: "formatUnit formats a value in a unit (bits/s, B/s, pps, flows/s, %, ms or count), with SI prefixes or, for bits and bytes, IEC ones if the system is iec.",
+		Category:    "formatting",
+	},
+	{
+		Name:        "importanceColor",
+		Signature:   "(platform string, severity ViewModelImportance) (interface{}, error)",
+		Description: "importanceColor returns the color of an importance level as a platform expects it: hex (also slack, mattermost and webex), discord (decimal integer) or msteams (Adaptive Card container style).",
+		Category:    "formatting",
+	},
+	{
+		Name:        "importanceEmoji",
+		Signature:   "(platform string, severity ViewModelImportance) (string, error)",
+		Description: "importanceEmoji returns the emoji(s) for an importance level as Slack shortcodes (slack, mattermost) or Unicode characters (unicode, also telegram, webex, msteams and discord).",
+		Category:    "formatting",
+	},
 	{
 		Name:        "importanceLabel",
 		Signature:   "(severity ViewModelImportance) string",
@@ -83,6 +136,12 @@ var functionMetadata = []*SchemaFunction{
 		Description: "importanceToEmoji returns the emoji(s) for an importance level.",
 		Category:    "formatting",
 	},
+	{
+		Name:        "incidentAction",
+		Signature:   "(platform string, event *EventViewModel) (string, error)",
+		Description: "incidentAction returns the action an incident management platform (pagerduty, opsgenie or servicenow) expects for the transition of the event.",
+		Category:    "utility",
+	},
 	{
 		Name:        "join",
 		Signature:   "(index int) string",
@@ -95,6 +154,24 @@ var functionMetadata = []*SchemaFunction{
 		Description: "joinWith returns the separator for index > 0, empty string for index 0.",
 		Category:    "utility",
 	},
+	{
+		Name:        "mapSeverity",
+		Signature:   "(platform string, severity interface{}, overrides ...interface{}) (string, error)",
+		Description: "mapSeverity maps an importance level or an AlarmSeverity detail value to the severity of a platform (pagerduty, opsgenie, servicenow, splunk-oncall, syslog or cef), overrides replacing individual entries of SeverityMappings, the last one winning.",
+		Category:    "formatting",
+	},
+	{
+		Name:        "param",
+		Signature:   "(name string, def interface{}) interface{}",
+		Description: "param returns the template parameter passed with the render request under name, or def when it is not passed.",
+		Category:    "utility",
+	},
+	{
+		Name:        "skip",
+		Signature:   "(reason string) (string, error)",
+		Description: "skip stops rendering and marks the notification as intentionally not sent.",
+		Category:    "utility",
+	},
 	{
 		Name:        "split",
 		Signature:   "(s string, sep string) []string",
@@ -140,6 +217,10 @@ var enumDefinitions = map[string]*SchemaEnum{
 		Values:      []string{"", "metric", "dimension", "url", "device", "device_labels", "device_label"},
 		Description: "DetailTag categorizes event details.",
 	},
+	"EventTransition": {
+		Values:      []string{"opened", "escalated", "de-escalated", "acknowledged", "resolved", "updated"},
+		Description: "EventTransition classifies the state change an event notifies about.",
+	},
 	"EventType": {
 		Values: []string{"alarm", "insight", "custom-insight", "synthetic", "mitigation", "generic"},
 	},
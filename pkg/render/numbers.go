@@ -0,0 +1,285 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"text/template"
+)
+
+// numberFuncs replace builtin functions of text/template once templates are
+// parsed, so that numbers compare by value whatever their type: detail values
+// decoded as json.Number, float64 fields and integer or float constants
+// alike, and so that printf formats json.Number with numeric verbs.
+var numberFuncs = template.FuncMap{
+	"eq":     compareEq,
+	"ne":     compareNe,
+	"lt":     compareLt,
+	"le":     compareLe,
+	"gt":     compareGt,
+	"ge":     compareGe,
+	"printf": printfNumbers,
+}
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// Errors of the builtin comparison functions, which are kept for operands
+// other than numbers.
+var (
+	errBadComparisonType = errors.New("invalid type for comparison")
+	errNoComparison      = errors.New("missing argument for comparison")
+)
+
+// comparableNumber returns the exact value of a number, json.Number
+// included, false for other values and NaN, which has no order.
+func comparableNumber(v reflect.Value) (*big.Float, bool) {
+	if v.Type() == jsonNumberType {
+		f, _, err := big.ParseFloat(v.String(), 10, 256, big.ToNearestEven)
+		return f, err == nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Float).SetUint64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) {
+			return nil, false
+		}
+		return new(big.Float).SetFloat64(v.Float()), true
+	}
+	return nil, false
+}
+
+// compareNumbers compares two operands when both are numbers.
+func compareNumbers(arg1, arg2 reflect.Value) (int, bool) {
+	if !arg1.IsValid() || !arg2.IsValid() {
+		return 0, false
+	}
+	n1, ok := comparableNumber(arg1)
+	if !ok {
+		return 0, false
+	}
+	n2, ok := comparableNumber(arg2)
+	if !ok {
+		return 0, false
+	}
+	return n1.Cmp(n2), true
+}
+
+// compareEq evaluates the comparison a == b || a == c || ...
+func compareEq(arg1 reflect.Value, arg2 ...reflect.Value) (bool, error) {
+	arg1 = indirectInterface(arg1)
+	if len(arg2) == 0 {
+		return false, errNoComparison
+	}
+	for _, arg := range arg2 {
+		truth, err := equal(arg1, indirectInterface(arg))
+		if truth || err != nil {
+			return truth, err
+		}
+	}
+	return false, nil
+}
+
+// compareNe evaluates the comparison a != b.
+func compareNe(arg1, arg2 reflect.Value) (bool, error) {
+	equal, err := compareEq(arg1, arg2)
+	return !equal, err
+}
+
+// compareLt evaluates the comparison a < b.
+func compareLt(arg1, arg2 reflect.Value) (bool, error) {
+	return less(indirectInterface(arg1), indirectInterface(arg2))
+}
+
+// compareLe evaluates the comparison a <= b.
+func compareLe(arg1, arg2 reflect.Value) (bool, error) {
+	lessThan, err := compareLt(arg1, arg2)
+	if lessThan || err != nil {
+		return lessThan, err
+	}
+	return compareEq(arg1, arg2)
+}
+
+// compareGt evaluates the comparison a > b.
+func compareGt(arg1, arg2 reflect.Value) (bool, error) {
+	lessOrEqual, err := compareLe(arg1, arg2)
+	if err != nil {
+		return false, err
+	}
+	return !lessOrEqual, nil
+}
+
+// compareGe evaluates the comparison a >= b.
+func compareGe(arg1, arg2 reflect.Value) (bool, error) {
+	lessThan, err := compareLt(arg1, arg2)
+	if err != nil {
+		return false, err
+	}
+	return !lessThan, nil
+}
+
+// equal compares numbers by value and other operands like the builtin eq.
+func equal(arg1, arg2 reflect.Value) (bool, error) {
+	if cmp, ok := compareNumbers(arg1, arg2); ok {
+		return cmp == 0, nil
+	}
+
+	k1, _ := basicKind(arg1)
+	k2, _ := basicKind(arg2)
+	if k1 != k2 {
+		switch {
+		case k1 == intKind && k2 == uintKind:
+			return arg1.Int() >= 0 && uint64(arg1.Int()) == arg2.Uint(), nil
+		case k1 == uintKind && k2 == intKind:
+			return arg2.Int() >= 0 && arg1.Uint() == uint64(arg2.Int()), nil
+		case arg1.IsValid() && arg2.IsValid():
+			return false, fmt.Errorf("incompatible types for comparison: %v and %v", arg1.Type(), arg2.Type())
+		}
+		return false, nil
+	}
+
+	switch k1 {
+	case boolKind:
+		return arg1.Bool() == arg2.Bool(), nil
+	case complexKind:
+		return arg1.Complex() == arg2.Complex(), nil
+	case floatKind:
+		return arg1.Float() == arg2.Float(), nil
+	case stringKind:
+		return arg1.String() == arg2.String(), nil
+	}
+	if arg1.Kind() != arg2.Kind() && arg1.IsValid() && arg2.IsValid() {
+		return false, fmt.Errorf("non-comparable types %s: %v, %s: %v", arg1, arg1.Type(), arg2.Type(), arg2)
+	}
+	if isNil(arg1) || isNil(arg2) {
+		return isNil(arg1) == isNil(arg2), nil
+	}
+	if !arg2.Type().Comparable() {
+		return false, fmt.Errorf("non-comparable type %s: %v", arg2, arg2.Type())
+	}
+	return arg1.Interface() == arg2.Interface(), nil
+}
+
+// less compares numbers by value and other operands like the builtin lt.
+func less(arg1, arg2 reflect.Value) (bool, error) {
+	if cmp, ok := compareNumbers(arg1, arg2); ok {
+		return cmp < 0, nil
+	}
+
+	k1, err := basicKind(arg1)
+	if err != nil {
+		return false, err
+	}
+	k2, err := basicKind(arg2)
+	if err != nil {
+		return false, err
+	}
+	if k1 != k2 {
+		switch {
+		case k1 == intKind && k2 == uintKind:
+			return arg1.Int() < 0 || uint64(arg1.Int()) < arg2.Uint(), nil
+		case k1 == uintKind && k2 == intKind:
+			return arg2.Int() >= 0 && arg1.Uint() < uint64(arg2.Int()), nil
+		}
+		return false, fmt.Errorf("incompatible types for comparison: %v and %v", arg1.Type(), arg2.Type())
+	}
+
+	switch k1 {
+	case floatKind:
+		return arg1.Float() < arg2.Float(), nil
+	case stringKind:
+		return arg1.String() < arg2.String(), nil
+	}
+	return false, errBadComparisonType
+}
+
+// kind is the kind of an operand of the builtin comparisons.
+type kind int
+
+const (
+	invalidKind kind = iota
+	boolKind
+	complexKind
+	intKind
+	floatKind
+	stringKind
+	uintKind
+)
+
+func basicKind(v reflect.Value) (kind, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return boolKind, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intKind, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintKind, nil
+	case reflect.Float32, reflect.Float64:
+		return floatKind, nil
+	case reflect.Complex64, reflect.Complex128:
+		return complexKind, nil
+	case reflect.String:
+		return stringKind, nil
+	}
+	return invalidKind, errBadComparisonType
+}
+
+// isNil tells whether v is the zero reflect.Value, or nil of its type.
+func isNil(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// indirectInterface returns the concrete value in an interface value, or
+// the zero reflect.Value for a nil interface.
+func indirectInterface(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Interface {
+		return v
+	}
+	if v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+// printfNumbers is fmt.Sprintf, formatting json.Number arguments as numbers.
+func printfNumbers(format string, args ...interface{}) string {
+	for i, arg := range args {
+		if n, ok := arg.(json.Number); ok {
+			args[i] = formattedNumber(n)
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// formattedNumber prints a json.Number as an integer or a float with numeric
+// verbs, e.g. %d or %.1f, and as the number text with other ones.
+type formattedNumber json.Number
+
+func (n formattedNumber) Format(f fmt.State, verb rune) {
+	var v interface{} = string(n)
+	switch verb {
+	case 'd', 'b', 'o', 'O', 'x', 'X', 'c', 'U':
+		if i, err := json.Number(n).Int64(); err == nil {
+			v = i
+		} else if x, err := json.Number(n).Float64(); err == nil {
+			v = x
+		}
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		if x, err := json.Number(n).Float64(); err == nil {
+			v = x
+		}
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), v)
+}
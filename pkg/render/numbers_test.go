@@ -0,0 +1,131 @@
+package render
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var numbersViewModel = json.RawMessage(`{
+	"CompanyID": 1228,
+	"Config": {},
+	"Events": [{
+		"Type": "alarm",
+		"IsActive": true,
+		"Details": [
+			{"Name": "FlowID", "Value": 1234567890123456789, "Tag": ""},
+			{"Name": "AlarmPolicyID", "Value": 123456789012, "Tag": ""},
+			{"Name": "bits", "Value": 58555.9140625, "Tag": "metric"},
+			{"Name": "packets", "Value": 5, "Tag": "metric"},
+			{"Name": "Nested", "Value": {"id": 98765432109876543}, "Tag": ""}
+		]
+	}]
+}`)
+
+func TestEventViewModelDetailNumbers(t *testing.T) {
+	var detail EventViewModelDetail
+	if err := json.Unmarshal([]byte(`{"Name": "FlowID", "Value": 1234567890123456789}`), &detail); err != nil {
+		t.Fatalf("Failed to unmarshal EventViewModelDetail: %v", err)
+	}
+	if detail.Value != json.Number("1234567890123456789") {
+		t.Errorf("Expected Value to keep all digits, got %#v", detail.Value)
+	}
+
+	jsonData, err := json.Marshal(detail)
+	if err != nil {
+		t.Fatalf("Failed to marshal EventViewModelDetail: %v", err)
+	}
+	if !strings.Contains(string(jsonData), `"Value":1234567890123456789`) {
+		t.Errorf("Expected Value to be marshaled as the same number, got %s", jsonData)
+	}
+}
+
+func Test_RenderNumbers(t *testing.T) {
+	tests := []struct {
+		template string
+		output   string
+	}{
+		{template: `{{ .Event.Details.GetValue "FlowID" }}`, output: "1234567890123456789"},
+		{template: `{{ toJSON (.Event.Details.GetValue "Nested") }}`, output: `{"id":98765432109876543}`},
+		{template: `{"id": {{ .Event.Details.GetValue "FlowID" | j }}}`, output: `{"id": 1234567890123456789}`},
+		{template: `{{ .Event.DedupKey }}`, output: "123456789012"},
+		{template: `{{ range .Event.Details.PrettifiedMetrics }}{{ .Value }} {{ .Label }};{{ end }}`, output: "58.56 kbits/s;5 pps;"},
+		{template: `{{ eq (.Event.Details.GetValue "packets") 5 }} {{ ne (.Event.Details.GetValue "packets") 5.0 }}`, output: "true false"},
+		{template: `{{ gt (.Event.Details.GetValue "bits") 58555 }} {{ le (.Event.Details.GetValue "bits") 1e4 }}`, output: "true false"},
+		{template: `{{ if .Event.Details.GetValue "packets" | lt 4 }}more{{ end }}`, output: "more"},
+		{template: `{{ eq (.Event.Details.GetValue "FlowID") 1234567890123456789 }} {{ eq (.Event.Details.GetValue "FlowID") 1234567890123456788 }}`, output: "true false"},
+		{template: `{{ eq .CompanyID 1228 }} {{ eq .Event.Type "alarm" }}`, output: "true true"},
+		{template: `{{ gt (.Event.Details.GetValue "FlowID") 100 }} {{ lt (.Event.Details.GetValue "FlowID") 1e19 }}`, output: "true true"},
+		{template: `{{ eq (.Event.Details.GetValue "FlowID") 1.5 }} {{ gt (.Event.Details.GetValue "packets") 4.5 }} {{ lt 4.5 (.Event.Details.GetValue "packets") }}`, output: "false true true"},
+		{template: `{{ eq (.Event.Details.GetValue "packets") 4 5.0 }} {{ ge (.Event.Details.GetValue "AlarmPolicyID") 123456789012.5 }}`, output: "true false"},
+		{template: `{{ printf "%.1f %d %08.2f %v %s" (.Event.Details.GetValue "packets") (.Event.Details.GetValue "FlowID") (.Event.Details.GetValue "bits") (.Event.Details.GetValue "packets") (.Event.Details.GetValue "FlowID") }}`, output: "5.0 1234567890123456789 58555.91 5 1234567890123456789"},
+	}
+
+	for _, tt := range tests {
+		resp := Render(RenderRequest{Template: tt.template, Data: numbersViewModel})
+		if resp.Error != "" || resp.Output != tt.output {
+			t.Errorf("Expected %q for %s, got %q (error %q)", tt.output, tt.template, resp.Output, resp.Error)
+		}
+	}
+}
+
+func Test_RenderNumbersJSON(t *testing.T) {
+	template := `{"id": {{ .Event.Details.GetValue "FlowID" }}, "text": "{{ .Event.Details.GetValue "FlowID" }}", {{ .Event.Details.GetValue "packets" }}: true}`
+	output := `{"id": 1234567890123456789, "text": "1234567890123456789", "5": true}`
+
+	resp := Render(RenderRequest{Template: template, JSON: true, Data: numbersViewModel})
+	if resp.Error != "" || resp.Output != output {
+		t.Errorf("Expected %q, got %q (error %q)", output, resp.Output, resp.Error)
+	}
+}
+
+func Test_RenderNumbers_Errors(t *testing.T) {
+	tests := []struct {
+		template string
+		error    string
+	}{
+		{template: `{{ lt .Event.Type 5 }}`, error: "error calling lt: incompatible types for comparison: string and int"},
+		{template: `{{ eq (.Event.Details.GetValue "packets") true }}`, error: "error calling eq: incompatible types for comparison: json.Number and bool"},
+		{template: `{{ gt true false }}`, error: "error calling gt: invalid type for comparison"},
+	}
+
+	for _, tt := range tests {
+		resp := Render(RenderRequest{Template: tt.template, Data: numbersViewModel})
+		if !strings.Contains(resp.Error, tt.error) {
+			t.Errorf("Expected error %q for %s, got %q", tt.error, tt.template, resp.Error)
+		}
+	}
+}
+
+func Test_CompareNumbers(t *testing.T) {
+	tests := []struct {
+		a, b interface{}
+		less bool
+		eq   bool
+	}{
+		{a: json.Number("1234567890123456789"), b: 100, less: false},
+		{a: json.Number("1234567890123456789"), b: json.Number("1234567890123456788"), less: false},
+		{a: json.Number("1234567890123456788"), b: int64(1234567890123456789), less: true},
+		{a: json.Number("1234567890123456789"), b: 1.5, less: false},
+		{a: uint64(1) << 63, b: 1e19, less: true},
+		{a: 5, b: 5.5, less: true},
+		{a: 5, b: json.Number("5.0"), eq: true},
+		{a: float32(0.5), b: json.Number("0.5"), eq: true},
+		{a: -1, b: uint64(1), less: true},
+		{a: ViewModelImportance_Critical, b: 7, eq: ViewModelImportance_Critical == 7, less: ViewModelImportance_Critical < 7},
+	}
+
+	for _, tt := range tests {
+		a, b := reflect.ValueOf(tt.a), reflect.ValueOf(tt.b)
+		if res, err := compareLt(a, b); err != nil || res != tt.less {
+			t.Errorf("Expected %#v < %#v to be %t, got %t (error %v)", tt.a, tt.b, tt.less, res, err)
+		}
+		if res, err := compareEq(a, b); err != nil || res != tt.eq {
+			t.Errorf("Expected %#v == %#v to be %t, got %t (error %v)", tt.a, tt.b, tt.eq, res, err)
+		}
+		if res, err := compareGt(b, a); err != nil || res != tt.less {
+			t.Errorf("Expected %#v > %#v to be %t, got %t (error %v)", tt.b, tt.a, tt.less, res, err)
+		}
+	}
+}
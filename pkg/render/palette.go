@@ -0,0 +1,169 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ImportanceStyle is how an importance level is presented in notifications.
+type ImportanceStyle struct {
+	// Color is a hex RGB color, e.g. #A82A2A
+	Color string `json:"color,omitempty"`
+	// Emoji is made of Slack emoji shortcodes, e.g. :red_circle:
+	Emoji string `json:"emoji,omitempty"`
+	// UnicodeEmoji is the Emoji as Unicode characters
+	UnicodeEmoji string `json:"unicodeEmoji,omitempty"`
+	// CardStyle is an Adaptive Card container style: default, accent, good, warning or attention
+	CardStyle string `json:"cardStyle,omitempty"`
+}
+
+// Palette maps importance levels to their style.
+type Palette map[ViewModelImportance]ImportanceStyle
+
+// DefaultPalette is the palette of Kentik Portal, used unless a render request overrides it.
+var DefaultPalette = Palette{
+	ViewModelImportance_None:     {Color: "#999999", CardStyle: "default"},
+	ViewModelImportance_Healthy:  {Color: "#1E9E1E", Emoji: ":warning: :large_green_circle:", UnicodeEmoji: "⚠️ 🟢", CardStyle: "good"},
+	ViewModelImportance_Notice:   {Color: "#157FF3", Emoji: ":warning: :large_blue_circle:", UnicodeEmoji: "⚠️ 🔵", CardStyle: "accent"},
+	ViewModelImportance_Minor:    {Color: "#F29D49", Emoji: ":warning: :large_purple_circle:", UnicodeEmoji: "⚠️ 🟣", CardStyle: "warning"},
+	ViewModelImportance_Warning:  {Color: "#EE7E0F", Emoji: ":warning: :large_brown_circle:", UnicodeEmoji: "⚠️ 🟤", CardStyle: "warning"},
+	ViewModelImportance_Major:    {Color: "#DB3737", Emoji: ":warning: :large_yellow_circle:", UnicodeEmoji: "⚠️ 🟡", CardStyle: "attention"},
+	ViewModelImportance_Severe:   {Color: "#C23030", Emoji: ":warning: :large_orange_circle:", UnicodeEmoji: "⚠️ 🟠", CardStyle: "attention"},
+	ViewModelImportance_Critical: {Color: "#A82A2A", Emoji: ":warning: :red_circle:", UnicodeEmoji: "⚠️ 🔴", CardStyle: "attention"},
+}
+
+// Color formats accepted by importanceColor, by platform.
+var colorFormats = map[string]func(ImportanceStyle) (interface{}, error){
+	"hex":        hexColor,
+	"slack":      hexColor,
+	"mattermost": hexColor,
+	"webex":      hexColor,
+	"discord":    decimalColor,
+	"msteams":    func(style ImportanceStyle) (interface{}, error) { return style.CardStyle, nil },
+}
+
+// Emoji formats accepted by importanceEmoji, by platform.
+var emojiFormats = map[string]func(ImportanceStyle) string{
+	"slack":      func(style ImportanceStyle) string { return style.Emoji },
+	"mattermost": func(style ImportanceStyle) string { return style.Emoji },
+	"unicode":    func(style ImportanceStyle) string { return style.UnicodeEmoji },
+	"telegram":   func(style ImportanceStyle) string { return style.UnicodeEmoji },
+	"webex":      func(style ImportanceStyle) string { return style.UnicodeEmoji },
+	"msteams":    func(style ImportanceStyle) string { return style.UnicodeEmoji },
+	"discord":    func(style ImportanceStyle) string { return style.UnicodeEmoji },
+}
+
+func hexColor(style ImportanceStyle) (interface{}, error) {
+	return style.Color, nil
+}
+
+// decimalColor converts the hex color to an integer, as Discord embeds expect.
+func decimalColor(style ImportanceStyle) (interface{}, error) {
+	if style.Color == "" {
+		return 0, nil
+	}
+	color, err := strconv.ParseUint(strings.TrimPrefix(style.Color, "#"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid color %q", style.Color)
+	}
+	return int(color), nil
+}
+
+// colors returns the hex colors of the palette.
+func (p Palette) colors() map[ViewModelImportance]string {
+	res := make(map[ViewModelImportance]string, len(p))
+	for importance, style := range p {
+		res[importance] = style.Color
+	}
+	return res
+}
+
+// emojis returns the Slack emoji shortcodes of the palette.
+func (p Palette) emojis() map[ViewModelImportance]string {
+	res := make(map[ViewModelImportance]string, len(p))
+	for importance, style := range p {
+		res[importance] = style.Emoji
+	}
+	return res
+}
+
+// withOverrides returns a copy of the palette with non-empty fields of the
+// overrides, keyed by importance name, replacing those of its styles.
+func (p Palette) withOverrides(overrides map[string]ImportanceStyle) (Palette, error) {
+	if len(overrides) == 0 {
+		return p, nil
+	}
+	levels := make(map[string]ViewModelImportance, len(ImportanceNames))
+	for importance, name := range ImportanceNames {
+		levels[name] = importance
+	}
+	res := make(Palette, len(p))
+	for importance, style := range p {
+		res[importance] = style
+	}
+	for name, override := range overrides {
+		importance, ok := levels[strings.ToLower(name)]
+		if !ok {
+			return nil, &ParamError{Name: "palette", Err: fmt.Errorf("unknown importance level %q in palette", name)}
+		}
+		style := res[importance]
+		if override.Color != "" {
+			style.Color = override.Color
+		}
+		if override.Emoji != "" {
+			style.Emoji = override.Emoji
+		}
+		if override.UnicodeEmoji != "" {
+			style.UnicodeEmoji = override.UnicodeEmoji
+		}
+		if override.CardStyle != "" {
+			style.CardStyle = override.CardStyle
+		}
+		res[importance] = style
+	}
+	return res, nil
+}
+
+// color formats the color of an importance level for the platform.
+func (p Palette) color(platform string, importance ViewModelImportance) (interface{}, error) {
+	format, ok := colorFormats[platform]
+	if !ok {
+		return nil, fmt.Errorf("unknown color platform %q, expected one of: %s", platform, formatNames(colorFormats))
+	}
+	return format(p[importance])
+}
+
+// emoji formats the emoji of an importance level for the platform.
+func (p Palette) emoji(platform string, importance ViewModelImportance) (string, error) {
+	format, ok := emojiFormats[platform]
+	if !ok {
+		return "", fmt.Errorf("unknown emoji platform %q, expected one of: %s", platform, formatNames(emojiFormats))
+	}
+	return format(p[importance]), nil
+}
+
+func formatNames[F any](formats map[string]F) string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// paletteFuncs returns importance functions bound to the palette of one execution.
+func paletteFuncs(p Palette) template.FuncMap {
+	return template.FuncMap{
+		"importanceColor": p.color,
+		"importanceEmoji": p.emoji,
+		"importanceToColor": func(importance ViewModelImportance) string {
+			return p[importance].Color
+		},
+		"importanceToEmoji": func(importance ViewModelImportance) string {
+			return p[importance].Emoji
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_RenderImportanceStyles(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		palette  map[string]ImportanceStyle
+		output   string
+		error    string
+	}{
+		{name: "Colors", template: `{{ importanceColor "hex" 7 }} {{ importanceColor "discord" 7 }} {{ importanceColor "msteams" 7 }} {{ importanceColor "msteams" 1 }}`, output: "#A82A2A 11020842 attention good"},
+		{name: "Emoji", template: `{{ importanceEmoji "slack" 7 }} {{ importanceEmoji "unicode" 7 }}`, output: ":warning: :red_circle: ⚠️ 🔴"},
+		{name: "Legacy functions", template: `{{ importanceToColor 1 }} {{ importanceToEmoji 1 }}`, output: "#1E9E1E :warning: :large_green_circle:"},
+		{
+			name:     "Palette overrides",
+			template: `{{ importanceColor "hex" 7 }} {{ importanceToColor 7 }} {{ importanceEmoji "unicode" 7 }} {{ importanceColor "hex" 6 }}`,
+			palette:  map[string]ImportanceStyle{"Critical": {Color: "#FF0000"}},
+			output:   "#FF0000 #FF0000 ⚠️ 🔴 #C23030",
+		},
+		{name: "Unknown platform", template: `{{ importanceColor "irc" 7 }}`, error: `unknown color platform "irc", expected one of: discord, hex, mattermost, msteams, slack, webex`},
+		{name: "Unknown palette level", template: `{{ importanceColor "hex" 7 }}`, palette: map[string]ImportanceStyle{"urgent": {Color: "#FF0000"}}, error: `Param error: unknown importance level "urgent" in palette`},
+		{name: "Invalid palette color", template: `{{ importanceColor "discord" 7 }}`, palette: map[string]ImportanceStyle{"critical": {Color: "red"}}, error: `invalid color "red"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels["alarm"], Palette: tt.palette})
+			if resp.Output != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, resp.Output)
+			}
+			if !strings.Contains(resp.Error, tt.error) || (tt.error == "") != (resp.Error == "") {
+				t.Errorf("Expected error %q, got %q", tt.error, resp.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/kentik/custom-notification-templates/templates"
+)
+
+// ParamError reports request params which do not match params declared by the template.
+type ParamError struct {
+	Name string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	return "Param error: " + e.Err.Error()
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateParams checks params against the params declared by a template and
+// returns them completed with defaults. Params which are not declared are
+// passed as they are, nil values count as not passed.
+func ValidateParams(specs []templates.ParamSpec, params map[string]interface{}) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(params)+len(specs))
+	for name, value := range params {
+		if value != nil {
+			res[name] = value
+		}
+	}
+
+	for _, spec := range specs {
+		value, ok := res[spec.Name]
+		if !ok {
+			if spec.Required {
+				return nil, &ParamError{Name: spec.Name, Err: fmt.Errorf("param %s is required", spec.Name)}
+			}
+			if spec.Default != nil {
+				res[spec.Name] = spec.Default
+			}
+			continue
+		}
+		if !spec.Accepts(value) {
+			return nil, &ParamError{Name: spec.Name, Err: fmt.Errorf("param %s must be of type %s, got %T", spec.Name, spec.TypeOrDefault(), value)}
+		}
+	}
+	return res, nil
+}
+
+// paramFunc returns the param function bound to params of one execution.
+func paramFunc(params map[string]interface{}) func(string, interface{}) interface{} {
+	return func(name string, def interface{}) interface{} {
+		if value, ok := params[name]; ok {
+			return value
+		}
+		return def
+	}
+}
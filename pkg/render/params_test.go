@@ -0,0 +1,122 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kentik/custom-notification-templates/templates"
+)
+
+func Test_RenderParams(t *testing.T) {
+	header := "{{- /*\n---\nparams:\n  - name: channel\n    required: true\n  - name: retries\n    type: integer\n    default: 3\n---\n*/ -}}\n"
+
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]interface{}
+		output   string
+		error    string
+	}{
+		{
+			name:     "Params and defaults",
+			template: header + `{{ .Params.channel }} {{ .Params.retries }} {{ param "team" "ops" }} {{ param "channel" "none" }}`,
+			params:   map[string]interface{}{"channel": "#alerts"},
+			output:   "#alerts 3 ops #alerts",
+		},
+		{
+			name:     "Undeclared params",
+			template: `{{ .Params.team }} {{ param "channel" "none" }}`,
+			params:   map[string]interface{}{"team": "ops"},
+			output:   "ops none",
+		},
+		{
+			name:     "Missing required param",
+			template: header + `{{ .Params.channel }}`,
+			error:    "Param error: param channel is required",
+		},
+		{
+			name:     "Param of wrong type",
+			template: header + `{{ .Params.channel }}`,
+			params:   map[string]interface{}{"channel": "#alerts", "retries": 1.5},
+			error:    "Param error: param retries must be of type integer, got float64",
+		},
+		{
+			name:     "Invalid front-matter",
+			template: "{{- /*\n---\nparams: [\n---\n*/ -}}",
+			error:    "invalid front-matter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels["alarm"], Params: tt.params})
+			if resp.Output != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, resp.Output)
+			}
+			if !strings.Contains(resp.Error, tt.error) || (tt.error == "") != (resp.Error == "") {
+				t.Errorf("Expected error %q, got %q", tt.error, resp.Error)
+			}
+		})
+	}
+
+	resp := Render(RenderRequest{Template: header, Data: TestingViewModels["alarm"]})
+	if resp.ErrorKind != ErrorKind_Param {
+		t.Errorf("Expected error kind %q, got %q", ErrorKind_Param, resp.ErrorKind)
+	}
+}
+
+func Test_RenderParams_PagerDuty(t *testing.T) {
+	source, err := templates.Asset("pagerduty.json.tmpl")
+	if err != nil {
+		t.Fatalf("Error reading template: %s", err)
+	}
+
+	for key, want := range map[string]string{"": "put-your-integration-key-here", `R0UT"NG`: `R0UT"NG`} {
+		req := RenderRequest{Name: "pagerduty.json.tmpl", Template: string(source), Data: TestingViewModels["alarm"]}
+		if key != "" {
+			req.Params = map[string]interface{}{"routing_key": key}
+		}
+		resp := Render(req)
+		var payload struct {
+			RoutingKey string `json:"routing_key"`
+		}
+		if err := json.Unmarshal([]byte(resp.Output), &payload); err != nil {
+			t.Fatalf("Error decoding output: %s (%s)", err, resp.Error)
+		}
+		if payload.RoutingKey != want {
+			t.Errorf("Expected routing key %q, got %q", want, payload.RoutingKey)
+		}
+	}
+}
+
+func Test_ValidateParams(t *testing.T) {
+	specs := []templates.ParamSpec{
+		{Name: "s"},
+		{Name: "n", Type: templates.ParamType_Number},
+		{Name: "i", Type: templates.ParamType_Integer},
+		{Name: "b", Type: templates.ParamType_Boolean},
+	}
+	valid := []map[string]interface{}{
+		{"s": "x", "n": 1.5, "i": 2.0, "b": true},
+		{"n": json.Number("1e3"), "i": json.Number("7"), "other": []string{}},
+		{"s": nil, "i": int64(1), "n": uint8(1)},
+	}
+	for _, params := range valid {
+		if _, err := ValidateParams(specs, params); err != nil {
+			t.Errorf("Unexpected error for %v: %s", params, err)
+		}
+	}
+	invalid := []map[string]interface{}{
+		{"s": 1},
+		{"n": "1"},
+		{"i": 1.5},
+		{"i": json.Number("1.5")},
+		{"b": "true"},
+	}
+	for _, params := range invalid {
+		if _, err := ValidateParams(specs, params); err == nil {
+			t.Errorf("Expected error for %v", params)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+	"text/template/parse"
+	"unicode/utf8"
+)
+
+// Range is a span of the template (or data) source. Lines and columns are
+// 1-based, columns count characters and the end position is exclusive.
+type Range struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// IsZero reports whether the range is unknown.
+func (r Range) IsZero() bool {
+	return r.StartLine == 0
+}
+
+var (
+	quotedTokenRegex  = regexp.MustCompile(`"([^"]+)"`)
+	keywordTokenRegex = regexp.MustCompile(`\{\{(\w+)\}\}`)
+)
+
+// rangeOf converts byte offsets in text into a Range.
+func rangeOf(text string, start, end int) Range {
+	startLine, startColumn := position(text, start)
+	endLine, endColumn := position(text, end)
+	return Range{StartLine: startLine, StartColumn: startColumn, EndLine: endLine, EndColumn: endColumn}
+}
+
+// position converts a byte offset in text into a line and a column.
+func position(text string, offset int) (int, int) {
+	offset = max(0, min(offset, len(text)))
+	lineStart := strings.LastIndex(text[:offset], "\n") + 1
+	line := 1 + strings.Count(text[:offset], "\n")
+	return line, 1 + utf8.RuneCountInString(text[lineStart:offset])
+}
+
+// lineBounds returns byte offsets of the start and the end (without the newline) of a 1-based line.
+func lineBounds(text string, line int) (int, int, bool) {
+	start := 0
+	for i := 1; i < line; i++ {
+		next := strings.IndexByte(text[start:], '\n')
+		if next < 0 {
+			return 0, 0, false
+		}
+		start += next + 1
+	}
+	end := strings.IndexByte(text[start:], '\n')
+	if end < 0 {
+		return start, len(text), true
+	}
+	return start, start + end, true
+}
+
+// action is a single {{ ... }} in template source.
+type action struct {
+	start, end int    // byte offsets, end is after the closing delimiter
+	keyword    string // first identifier, e.g. if, range, end; empty for other actions and comments
+	closed     bool
+}
+
+// scanActions finds all actions in text, skipping over comments and quoted strings like the template lexer does.
+func scanActions(text string) []action {
+	var result []action
+	pos := 0
+	for {
+		open := strings.Index(text[pos:], "{{")
+		if open < 0 {
+			return result
+		}
+		a := action{start: pos + open, end: len(text)}
+		i := a.start + 2
+		if strings.HasPrefix(text[i:], "- ") || strings.HasPrefix(text[i:], "-\t") || strings.HasPrefix(text[i:], "-\n") {
+			i++
+		}
+		for i < len(text) && strings.ContainsRune(" \t\r\n", rune(text[i])) {
+			i++
+		}
+
+		if strings.HasPrefix(text[i:], "/*") {
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				if closeAt := strings.Index(text[i+2+end:], "}}"); closeAt >= 0 {
+					a.end, a.closed = i+2+end+closeAt+2, true
+				}
+			}
+			result = append(result, a)
+			if !a.closed {
+				return result
+			}
+			pos = a.end
+			continue
+		}
+
+		wordEnd := i
+		for wordEnd < len(text) && (text[wordEnd] == '_' || isAlphaNumeric(text[wordEnd])) {
+			wordEnd++
+		}
+		a.keyword = text[i:wordEnd]
+
+		for j := wordEnd; j < len(text); j++ {
+			switch text[j] {
+			case '"', '`', '\'':
+				j = skipQuoted(text, j)
+			case '}':
+				if strings.HasPrefix(text[j:], "}}") {
+					a.end, a.closed = j+2, true
+				}
+			}
+			if a.closed {
+				break
+			}
+		}
+		result = append(result, a)
+		if !a.closed {
+			return result
+		}
+		pos = a.end
+	}
+}
+
+// skipQuoted returns the offset of the quote closing the one at text[start].
+func skipQuoted(text string, start int) int {
+	quote := text[start]
+	for j := start + 1; j < len(text); j++ {
+		switch {
+		case text[j] == '\\' && quote != '`':
+			j++
+		case text[j] == quote, text[j] == '\n' && quote != '`':
+			return j
+		}
+	}
+	return len(text)
+}
+
+func isAlphaNumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// unmatchedBlock returns the innermost block action that is missing its {{end}}.
+func unmatchedBlock(actions []action) (action, bool) {
+	var stack []action
+	for _, a := range actions {
+		switch a.keyword {
+		case "if", "range", "with", "define", "block":
+			stack = append(stack, a)
+		case "end":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if len(stack) == 0 {
+		return action{}, false
+	}
+	return stack[len(stack)-1], true
+}
+
+// parseErrorRange locates a parse error reported at line in text.
+func parseErrorRange(text string, line int, msg string) Range {
+	actions := scanActions(text)
+
+	switch {
+	case strings.Contains(msg, "unclosed action"), strings.Contains(msg, "unclosed comment"):
+		if len(actions) > 0 && !actions[len(actions)-1].closed {
+			return rangeOf(text, actions[len(actions)-1].start, len(text))
+		}
+	case strings.Contains(msg, "unexpected EOF"):
+		if block, ok := unmatchedBlock(actions); ok {
+			return rangeOf(text, block.start, len(text))
+		}
+	}
+
+	lineStart, lineEnd, ok := lineBounds(text, line)
+	if !ok {
+		return Range{}
+	}
+
+	var candidates []action
+	for _, a := range actions {
+		if a.start <= lineEnd && a.end > lineStart {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return rangeOf(text, lineStart, lineEnd)
+	}
+
+	// prefer the action containing the token the message complains about
+	if matches := keywordTokenRegex.FindStringSubmatch(msg); matches != nil {
+		for _, a := range candidates {
+			if a.keyword == matches[1] {
+				return rangeOf(text, a.start, a.end)
+			}
+		}
+	}
+	if matches := quotedTokenRegex.FindStringSubmatch(msg); matches != nil {
+		for _, a := range candidates {
+			if strings.Contains(text[a.start:a.end], matches[1]) {
+				return rangeOf(text, a.start, a.end)
+			}
+		}
+	}
+	return rangeOf(text, candidates[0].start, candidates[0].end)
+}
+
+// execErrorRange locates an execution error. The position comes from the
+// error message, or failing that from the node matching the action text.
+func execErrorRange(text string, tree *parse.Tree, line, column int, actionText string) Range {
+	offset := -1
+	if lineStart, _, ok := lineBounds(text, line); ok && line > 0 {
+		offset = lineStart + column
+	} else if tree != nil && actionText != "" {
+		walkTree(tree.Root, func(node parse.Node) {
+			if offset < 0 && node.String() == actionText {
+				offset = int(node.Position())
+			}
+		})
+	}
+	if offset < 0 {
+		return Range{}
+	}
+
+	for _, a := range scanActions(text) {
+		if a.start <= offset && offset < a.end {
+			return rangeOf(text, a.start, a.end)
+		}
+	}
+	return rangeOf(text, offset, offset)
+}
@@ -0,0 +1,127 @@
+package render
+
+import (
+	"testing"
+)
+
+func Test_ErrorRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     Range
+	}{
+		{
+			name:     "Unclosed action",
+			template: "Line 1\nLine 2 {{ .Invalid",
+			want:     Range{StartLine: 2, StartColumn: 8, EndLine: 2, EndColumn: 19},
+		},
+		{
+			name:     "Unclosed block",
+			template: "{{ .CompanyName }}\n{{- if .IsSingleEvent }}\nfoo\n",
+			want:     Range{StartLine: 2, StartColumn: 1, EndLine: 4, EndColumn: 1},
+		},
+		{
+			name:     "Unexpected end",
+			template: "{{ .CompanyName }} {{ end }}",
+			want:     Range{StartLine: 1, StartColumn: 20, EndLine: 1, EndColumn: 29},
+		},
+		{
+			name:     "Undefined function",
+			template: "{{ .CompanyName }} {{ foo .CompanyID }}",
+			want:     Range{StartLine: 1, StartColumn: 20, EndLine: 1, EndColumn: 40},
+		},
+		{
+			name:     "Missing field",
+			template: "→ {{ .CompanyName }}\n  {{ .Event.Foo }} {{ .CompanyID }}",
+			want:     Range{StartLine: 2, StartColumn: 3, EndLine: 2, EndColumn: 19},
+		},
+		{
+			name:     "Missing field in block pipeline",
+			template: "{{/* {{ }} */}}\n{{ if .Event.Foo }}x{{ end }}",
+			want:     Range{StartLine: 2, StartColumn: 1, EndLine: 2, EndColumn: 20},
+		},
+		{
+			name:     "Quoted delimiters",
+			template: `{{ printf "}}" }}{{ .Event.Foo "}}" }}`,
+			want:     Range{StartLine: 1, StartColumn: 18, EndLine: 1, EndColumn: 39},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels["alarm"]})
+			if resp.Error == "" {
+				t.Fatal("Expected error")
+			}
+			got := Range{StartLine: resp.StartLine, EndLine: resp.EndLine}
+			if resp.StartColumn != nil && resp.EndColumn != nil {
+				got.StartColumn, got.EndColumn = *resp.StartColumn, *resp.EndColumn
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v for %s", tt.want, got, resp.Error)
+			}
+		})
+	}
+}
+
+func Test_ErrorRanges_Sections(t *testing.T) {
+	resp := Render(RenderRequest{
+		Template: `{{ define "greeting" }}Hi {{ .Event.Foo }}{{ end }}`,
+		Sections: map[string]string{
+			Section_Subject: "{{ .CompanyName }}\n{{ template \"greeting\" . }}",
+		},
+		Data: TestingViewModels["alarm"],
+	})
+
+	subject := resp.Sections[Section_Subject]
+	if subject.TemplateName != "greeting" {
+		t.Errorf("Expected error in greeting, got %q", subject.TemplateName)
+	}
+	if subject.StartLine != 1 || *subject.StartColumn != 27 || *subject.EndColumn != 43 {
+		t.Errorf("Expected range within the define block, got %d:%d-%d", subject.StartLine, *subject.StartColumn, *subject.EndColumn)
+	}
+}
+
+func Test_DataErrorRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantPath string
+		want     Range
+	}{
+		{
+			name:     "Syntax error",
+			data:     "{\n  \"CompanyID\": 1,\n  \"Events\": [{\"Type\": \"alarm\",}]\n}",
+			wantPath: "$.Events[0]",
+			want:     Range{StartLine: 3, StartColumn: 31, EndLine: 3, EndColumn: 32},
+		},
+		{
+			name:     "Top-level type mismatch",
+			data:     "{\n  \"CompanyID\": \"1002\"\n}",
+			wantPath: "$.CompanyID",
+			want:     Range{StartLine: 2, StartColumn: 16, EndLine: 2, EndColumn: 22},
+		},
+		{
+			name:     "Nested type mismatch",
+			data:     "{\"Events\": [\n  {\"Type\": \"alarm\"},\n  {\"Type\": \"alarm\", \"Details\": [{\"Name\": \"x\", \"Tag\": 1}]}\n]}",
+			wantPath: "$.Events[1].Details[0].Tag",
+			want:     Range{StartLine: 3, StartColumn: 54, EndLine: 3, EndColumn: 55},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: "{{ .CompanyID }}", Data: []byte(tt.data)})
+			if resp.ErrorKind != ErrorKind_Data || resp.Pane != Pane_Data {
+				t.Fatalf("Expected data error in data pane, got %q in %q: %s", resp.ErrorKind, resp.Pane, resp.Error)
+			}
+			if resp.DataPath != tt.wantPath {
+				t.Errorf("Expected path %q, got %q", tt.wantPath, resp.DataPath)
+			}
+			got := Range{StartLine: resp.StartLine, StartColumn: *resp.StartColumn, EndLine: resp.EndLine, EndColumn: *resp.EndColumn}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,68 +2,472 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"text/template/parse"
 	"time"
+
+	"github.com/kentik/custom-notification-templates/templates"
 )
 
 var lineColumnRegex = regexp.MustCompile(`template:\s*[^:]+:(\d+)(?::(\d+))?`)
 
+// Panes the error range fields of RenderResponse can refer to.
+const (
+	Pane_Template = "template"
+	Pane_Data     = "data"
+	Pane_Output   = "output"
+)
+
+// Well-known section names for channels that need more than one rendered artifact.
+const (
+	Section_Subject = "subject"
+	Section_Body    = "body"
+	Section_Headers = "headers"
+	Section_Query   = "query"
+)
+
 type RenderRequest struct {
 	Name     string          `json:"name"`
 	Template string          `json:"template"`
 	Data     json.RawMessage `json:"data"`
+
+	// Sections holds named templates (subject, body, headers, ...) rendered
+	// against the same data. All sections share define blocks with each other
+	// and with Template, which then only serves as a place for common helpers.
+	Sections map[string]string `json:"sections,omitempty"`
+
+	// JSON renders the output as JSON: values printed by actions are escaped
+	// for their place in the JSON and, in strict mode, the output must parse.
+	// It applies to Template, or to all sections when there are any.
+	// JSONSections names the sections rendered as JSON when only some of them
+	// are, e.g. a body but no subject. A Name ending in .json.tmpl only has
+	// the output checked in strict mode, so that templates escaping values
+	// themselves, e.g. with toJSON, keep working.
+	JSON         bool     `json:"json,omitempty"`
+	JSONSections []string `json:"jsonSections,omitempty"`
+
+	// Strict turns problems the default rendering tolerates into errors:
+	// unknown payload fields, missing map keys, Get or GetValue of an absent
+	// detail, a nil Event used other than in a condition, and JSON output
+	// (see JSON) which is not valid JSON.
+	Strict bool `json:"strict,omitempty"`
+
+	// Params holds template parameters, such as a routing key or a channel
+	// name, available as .Params and through the param function. They are
+	// checked against params declared in the front-matter of Template.
+	Params map[string]interface{} `json:"params,omitempty"`
+
+	// FanOut renders the template once per event, for receivers that take
+	// one request per event. Each run sees a single .Event, the whole
+	// notification stays available as .Digest. Results are in Events.
+	FanOut bool `json:"fanOut,omitempty"`
+
+	// Preview asks for RenderResponse.Preview, and warnings about tokens
+	// inlined in the template, for output shown to people, e.g. in an editor.
+	// The preview is also returned whenever values of secret params are passed.
+	Preview bool `json:"preview,omitempty"`
+
+	// Palette overrides styles of DefaultPalette by importance name, e.g.
+	// {"critical": {"color": "#FF0000"}}, for white-labelled deployments.
+	// Only non-empty fields of a style replace the default ones.
+	Palette map[string]ImportanceStyle `json:"palette,omitempty"`
 }
 
 type RenderResponse struct {
 	Output string `json:"output"`
 	Error  string `json:"error,omitempty"`
 
+	// Preview is Output with values of secret params and anything shaped
+	// like a token masked, safe to be shown or shared. It is only returned
+	// for requests asking for it and those passing secret params.
+	Preview string `json:"preview,omitempty"`
+
+	// Skipped is set when the template called skip: the notification should
+	// not be sent, for the given reason, and there is no output. A section
+	// calling skip skips the whole notification: rendering stops there and
+	// Sections only holds that section, other sections have no output.
+	Skipped    bool   `json:"skipped,omitempty"`
+	SkipReason string `json:"skipReason,omitempty"`
+
+	// machine-readable error details: one of ErrorKind_*, the offending
+	// template or section name and the failing action, if known
+	ErrorKind    string `json:"errorKind,omitempty"`
+	TemplateName string `json:"templateName,omitempty"`
+	Action       string `json:"action,omitempty"`
+
+	// Pane tells whether the range fields refer to the template, the data or
+	// the output, DataPath is the JSONPath of the offending value for data and
+	// output errors
+	Pane     string `json:"pane,omitempty"`
+	DataPath string `json:"dataPath,omitempty"`
+
 	// simple fields
 	Line   int  `json:"line,omitempty"`
 	Column *int `json:"column,omitempty"`
 
-	// more sophisticated error handling
+	// more sophisticated error handling: the whole failing action or block,
+	// columns are 1-based and the end position is exclusive
 	StartLine   int  `json:"startLine,omitempty"`
 	StartColumn *int `json:"startColumn,omitempty"`
 	EndLine     int  `json:"endLine,omitempty"`
 	EndColumn   *int `json:"endColumn,omitempty"`
+
+	// non-fatal problems noticed while rendering, such as missing details
+	Warnings []Warning `json:"warnings,omitempty"`
+
+	// per-section results, only set when the request has sections
+	Sections map[string]RenderResponse `json:"sections,omitempty"`
+
+	// per-event results in payload order, only set for fan-out requests
+	Events []RenderResponse `json:"events,omitempty"`
+
+	err error
+}
+
+// Err returns the error behind the Error message, or nil on success.
+// It is one of *ParseError, *ExecError, *DataError, *LimitError or *OutputError.
+func (resp RenderResponse) Err() error {
+	return resp.err
 }
 
 func Render(req RenderRequest) RenderResponse {
+	return RenderContext(context.Background(), req, RenderOptions{})
+}
+
+// RenderContext renders the request, stopping when ctx is done or a limit from opts is hit.
+// In those cases the response error is a *LimitError.
+func RenderContext(ctx context.Context, req RenderRequest, opts RenderOptions) RenderResponse {
+	tmpl, err := parseTemplate(req)
+	if err != nil {
+		return renderErr(err)
+	}
+	return tmpl.render(ctx, req, opts)
+}
+
+// parsedTemplate is the main template together with all sections parsed into the same set.
+type parsedTemplate struct {
+	root          *template.Template
+	sections      []string
+	sectionErrors map[string]error
+	sources       map[string]string
+	json          map[string]bool // templates and sections rendered as JSON
+	sites         []warningSite
+	params        []templates.ParamSpec
+
+	// warnings about inlined tokens, found on the first render asking for them
+	tokensOnce sync.Once
+	tokens     []Warning
+
+	// the request parsed, without data, to restore contexts of errors
+	req          RenderRequest
+	contextsOnce sync.Once
+	contexts     map[contextKey]string
+}
+
+func parseTemplate(req RenderRequest) (*parsedTemplate, error) {
+	return parseInstrumented(req, nil)
+}
+
+// parseInstrumented parses and instruments the template and its sections,
+// keeping the text of rewritten nodes in origins, unless nil.
+func parseInstrumented(req RenderRequest, origins nodeOrigins) (*parsedTemplate, error) {
 	name := req.Name
 	if name == "" {
 		name = "template"
 	}
-	tmpl, err := template.New(name).
+	root, err := template.New(name).
 		Funcs(TextTemplateFuncMap).
 		Parse(req.Template)
 
 	if err != nil {
+		return nil, newParseError(name, req.Template, err)
+	}
+
+	res := &parsedTemplate{
+		root:          root,
+		sectionErrors: make(map[string]error),
+		sources:       map[string]string{name: req.Template},
+		req: RenderRequest{
+			Name:         req.Name,
+			Template:     req.Template,
+			Sections:     req.Sections,
+			JSON:         req.JSON,
+			JSONSections: req.JSONSections,
+		},
+	}
+	info, err := templates.ParseInfo(name, []byte(req.Template))
+	if err != nil && !errors.Is(err, templates.ErrNoFrontMatter) {
+		return nil, &ParseError{Name: name, Err: err}
+	}
+	res.params = info.Params
+
+	names := make([]string, 0, len(req.Sections))
+	for section := range req.Sections {
+		names = append(names, section)
+	}
+	sort.Strings(names)
+
+	for _, section := range names {
+		if section == name {
+			res.sectionErrors[section] = newParseError(section, "", fmt.Errorf("section name %q collides with the template name", section))
+			continue
+		}
+		if _, err := root.New(section).Parse(req.Sections[section]); err != nil {
+			res.sectionErrors[section] = newParseError(section, req.Sections[section], err)
+			continue
+		}
+		res.sections = append(res.sections, section)
+		res.sources[section] = req.Sections[section]
+	}
+
+	if origins != nil {
+		origins.snapshot(root)
+	}
+	res.sites = instrument(root, res.sources, origins)
+	res.json, err = jsonOutputs(req, name, res.sections, res.sectionErrors)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.json) > 0 {
+		var jsonNames, plainNames []string
+		for _, output := range append([]string{name}, res.sections...) {
+			if res.json[output] {
+				jsonNames = append(jsonNames, output)
+			} else if output != name || !res.hasSections() {
+				plainNames = append(plainNames, output)
+			}
+		}
+		if err := escapeJSON(root, jsonNames, plainNames, res.sources); err != nil {
+			return nil, err
+		}
+	}
+	root.Funcs(internalFuncMap).Funcs(numberFuncs)
+	return res, nil
+}
+
+// jsonOutputs returns the set of outputs of the request rendered as JSON:
+// Template, or all sections when there are any, for a JSON request, and
+// the JSONSections. Sections which failed to parse are left out.
+func jsonOutputs(req RenderRequest, name string, sections []string, sectionErrors map[string]error) (map[string]bool, error) {
+	res := make(map[string]bool)
+	if req.JSON {
+		if len(req.Sections) == 0 {
+			res[name] = true
+		}
+		for _, section := range sections {
+			res[section] = true
+		}
+	}
+	for _, section := range req.JSONSections {
+		if _, ok := req.Sections[section]; !ok {
+			return nil, &ParseError{Name: section, Err: fmt.Errorf("JSON section %q is not one of the sections", section)}
+		}
+		if _, failed := sectionErrors[section]; !failed {
+			res[section] = true
+		}
+	}
+	return res, nil
+}
+
+// render decodes the request payload and executes the template or all of its
+// sections. It is safe to call concurrently on the same parsedTemplate.
+func (t *parsedTemplate) render(ctx context.Context, req RenderRequest, opts RenderOptions) RenderResponse {
+	state := newExecState(ctx, opts)
+	state.sites = t.sites
+	state.strict = req.Strict
+	if err := state.checkContext(); err != nil {
 		return renderErr(err)
 	}
 
-	ctx, _, parseErr := buildContext(req.Data)
-	if parseErr != nil {
-		return RenderResponse{Error: "Data parse error: " + parseErr.Error()}
+	vm, _, err := buildContext(req.Data)
+	if err != nil {
+		return renderErr(newDataError(req.Data, err))
 	}
+	vm.Params, err = ValidateParams(t.params, req.Params)
+	if err != nil {
+		return renderErr(err)
+	}
+	palette, err := DefaultPalette.withOverrides(req.Palette)
+	if err != nil {
+		return renderErr(err)
+	}
+
+	// unknown fields come from newer payloads, they are kept as Extra
+	var dataWarnings []Warning
+	if fields := unknownFieldsOf(vm); len(fields) > 0 {
+		if req.Strict {
+			return renderErr(unknownFieldError(req.Data, fields[0]))
+		}
+		dataWarnings = unknownFieldWarnings(req.Data, fields)
+	}
+
+	// bind per-execution functions on a clone, the parsed trees stay shared
+	tmpl, err := t.root.Clone()
+	if err != nil {
+		return renderErr(t.execError(t.root.Name(), err))
+	}
+	tmpl.Funcs(template.FuncMap{
+		rangeGuardFunc: state.guardRange,
+		detailFunc:     state.detail,
+		eventFunc:      state.event,
+		"param":        paramFunc(vm.Params),
+	})
+	tmpl.Funcs(paletteFuncs(palette))
+	if req.Strict {
+		tmpl.Option("missingkey=error")
+	}
+
+	// scanning for tokens is costly, it is left out unless the output is shown
+	secrets := t.secretValues(vm.Params)
+	preview := req.Preview || len(secrets) > 0
+	if req.Preview {
+		dataWarnings = append(dataWarnings, t.inlinedTokens()...)
+	}
+	if req.FanOut {
+		resp := RenderResponse{Events: make([]RenderResponse, 0, len(vm.RawEvents)), Warnings: dataWarnings}
+		for _, event := range vm.RawEvents {
+			res := t.executeAll(tmpl, vm.forEvent(event), req.Strict, state)
+			if preview {
+				res.setPreview(secrets)
+			}
+			resp.Events = append(resp.Events, res)
+		}
+		return resp
+	}
+	resp := t.executeAll(tmpl, vm, req.Strict, state)
+	if preview {
+		resp.setPreview(secrets)
+	}
+	resp.Warnings = append(dataWarnings, resp.Warnings...)
+	return resp
+}
+
+// executeAll executes the template or all of its sections against vm.
+func (t *parsedTemplate) executeAll(tmpl *template.Template, vm *NotificationViewModel, strict bool, state *execState) RenderResponse {
+	if t.hasSections() {
+		return t.executeSections(tmpl, vm, strict, state)
+	}
+	return t.validate(tmpl.Name(), t.execute(tmpl, tmpl.Name(), vm, state), strict)
+}
+
+// validate turns JSON output of the named template which does not parse
+// into an error, in strict mode.
+func (t *parsedTemplate) validate(name string, resp RenderResponse, strict bool) RenderResponse {
+	if !strict || !t.json[name] && !isJSONTemplate(t.req.Name) || resp.Error != "" || resp.Skipped {
+		return resp
+	}
+	if err := validateJSON(name, resp.Output); err != nil {
+		warnings := resp.Warnings
+		resp = renderErr(err)
+		resp.Warnings = warnings
+	}
+	return resp
+}
+
+func (t *parsedTemplate) hasSections() bool {
+	return len(t.sections) > 0 || len(t.sectionErrors) > 0
+}
 
+func (t *parsedTemplate) execute(tmpl *template.Template, name string, vm *NotificationViewModel, state *execState) RenderResponse {
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, ctx); err != nil {
-		return renderErr(err)
+	err := tmpl.ExecuteTemplate(state.writer(&buf), name, vm)
+	if err == nil {
+		err = state.stopped
+	}
+	if err != nil {
+		var skipErr *skipError
+		if errors.As(err, &skipErr) {
+			return RenderResponse{Skipped: true, SkipReason: skipErr.reason, Warnings: state.takeWarnings()}
+		}
+		resp := renderErr(t.execError(name, err))
+		resp.Warnings = state.takeWarnings()
+		return resp
 	}
 
-	return RenderResponse{Output: buf.String()}
+	return RenderResponse{Output: buf.String(), Warnings: state.takeWarnings()}
+}
+
+// execError wraps an execution error, locating it in the source it came from.
+func (t *parsedTemplate) execError(name string, err error) *ExecError {
+	var siteErr *siteError
+	if errors.As(err, &siteErr) && siteErr.site < len(t.sites) {
+		err = t.sites[siteErr.site].restore(err)
+	}
+	err = t.restoreContext(err)
+	res := newExecError(name, err)
+	if text, ok := t.sources[res.Source]; ok {
+		var tree *parse.Tree
+		if tmpl := t.root.Lookup(res.Name); tmpl != nil {
+			tree = tmpl.Tree
+		}
+		res.Range = execErrorRange(text, tree, res.Line, res.Column, res.Action)
+	}
+	return res
+}
+
+// restoreContext puts back the parsed text of a rewritten node quoted by an
+// execution error. Texts are only needed for errors, so they are recorded by
+// parsing the template anew the first time, rewriting it the same way.
+func (t *parsedTemplate) restoreContext(err error) error {
+	msg := err.Error()
+	matches := execErrorRegex.FindStringSubmatchIndex(msg)
+	if matches == nil || matches[4] < 0 {
+		return err
+	}
+	source, quoted := msg[matches[2]:matches[3]], msg[matches[10]:matches[11]]
+	line, _ := strconv.Atoi(msg[matches[4]:matches[5]])
+	column, _ := strconv.Atoi(msg[matches[6]:matches[7]])
+	lineStart, _, ok := lineBounds(t.sources[source], line)
+	if !ok {
+		return err
+	}
+	t.contextsOnce.Do(func() {
+		origins := make(nodeOrigins)
+		if _, err := parseInstrumented(t.req, origins); err == nil {
+			t.contexts = origins.contexts()
+		}
+	})
+	text, ok := t.contexts[contextKey{name: source, pos: lineStart + column, text: quoted}]
+	if !ok {
+		return err
+	}
+	return &restoredError{msg: msg[:matches[10]] + text + msg[matches[11]:], err: err}
+}
+
+func (t *parsedTemplate) executeSections(tmpl *template.Template, vm *NotificationViewModel, strict bool, state *execState) RenderResponse {
+	resp := RenderResponse{
+		Sections: make(map[string]RenderResponse, len(t.sections)+len(t.sectionErrors)),
+	}
+	for section, err := range t.sectionErrors {
+		resp.Sections[section] = renderErr(err)
+	}
+	for _, section := range t.sections {
+		res := t.validate(section, t.execute(tmpl, section, vm, state), strict)
+		if res.Skipped {
+			return RenderResponse{
+				Skipped:    true,
+				SkipReason: res.SkipReason,
+				Sections:   map[string]RenderResponse{section: res},
+			}
+		}
+		resp.Sections[section] = res
+	}
+	return resp
 }
 
 func buildContext(data json.RawMessage) (*NotificationViewModel, bool, error) {
 	var res NotificationViewModel
 
 	dec := json.NewDecoder(strings.NewReader(string(data)))
-	dec.DisallowUnknownFields()
 
 	if err := dec.Decode(&res); err != nil {
 		return nil, false, err
@@ -79,25 +483,76 @@ func renderErr(err error) RenderResponse {
 	errMsg := err.Error()
 	line, col := extractLineColumn(errMsg)
 
+	var (
+		parseErr  *ParseError
+		execErr   *ExecError
+		dataErr   *DataError
+		outputErr *OutputError
+	)
+	if errors.As(err, &execErr) && execErr.Line > 0 {
+		line, col = execErr.Line, execErr.Column
+	}
+
 	var colPtr *int
 	if col > 0 {
 		colPtr = &col
 	}
 
 	resp := RenderResponse{
-		Error:  errMsg,
-		Line:   line,
-		Column: colPtr,
+		Error:     errMsg,
+		ErrorKind: errorKind(err),
+		Line:      line,
+		Column:    colPtr,
 		// range fields - start position is known, end position defaults to same line
 		StartLine:   line,
 		StartColumn: colPtr,
 		EndLine:     line,
 		EndColumn:   colPtr,
+
+		err: err,
+	}
+
+	var rng Range
+	if errors.As(err, &parseErr) {
+		resp.TemplateName = parseErr.Name
+		rng = parseErr.Range
+	}
+	if errors.As(err, &execErr) {
+		resp.TemplateName = execErr.Name
+		resp.Action = execErr.Action
+		rng = execErr.Range
+	}
+	if !rng.IsZero() {
+		resp.Pane = Pane_Template
+		resp.setRange(rng)
+	}
+	if errors.As(err, &dataErr) {
+		resp.DataPath = dataErr.Path
+		if !dataErr.Range.IsZero() {
+			resp.Pane = Pane_Data
+			resp.setRange(dataErr.Range)
+		}
+	}
+	if errors.As(err, &outputErr) {
+		resp.TemplateName = outputErr.Name
+		resp.DataPath = outputErr.Path
+		if !outputErr.Range.IsZero() {
+			resp.Pane = Pane_Output
+			resp.setRange(outputErr.Range)
+		}
 	}
 
 	return resp
 }
 
+// setRange fills the range fields, columns are 1-based and the end is exclusive.
+func (resp *RenderResponse) setRange(rng Range) {
+	resp.StartLine = rng.StartLine
+	resp.StartColumn = &rng.StartColumn
+	resp.EndLine = rng.EndLine
+	resp.EndColumn = &rng.EndColumn
+}
+
 func extractLineColumn(errMsg string) (int, int) {
 	//  - "template: subject:4: ..."
 	//  - "template: body:7:3: ..."
@@ -7,6 +7,9 @@ import (
 	"path"
 	"strings"
 	"testing"
+
+	"github.com/kentik/custom-notification-templates/pkg/schemas"
+	"github.com/kentik/custom-notification-templates/templates"
 )
 
 type TemplateEntry struct {
@@ -46,11 +49,17 @@ func Test_AllExamples_Render(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error reading template file %s: %s", entry.Name, err)
 		}
+		info, err := templates.Get(entry.Name)
+		if err != nil {
+			t.Fatalf("Error reading template info: %s", err)
+		}
 
 		for modelName, model := range TestingViewModels {
 			req := RenderRequest{
+				Name:     entry.Name,
 				Template: string(templateContent),
 				Data:     model,
+				Preview:  true,
 			}
 			resp := Render(req)
 			if resp.Error != "" {
@@ -59,7 +68,7 @@ func Test_AllExamples_Render(t *testing.T) {
 
 			result := resp.Output
 			outputPath := fmt.Sprintf("../../output/%s-%s", modelName, strings.TrimSuffix(entry.Name, ".tmpl"))
-			os.WriteFile(outputPath, []byte(result), 0644)
+			os.WriteFile(outputPath, []byte(resp.Preview), 0644)
 			t.Logf("Wrote output to %s", outputPath)
 
 			if entry.IsJson {
@@ -69,8 +78,214 @@ func Test_AllExamples_Render(t *testing.T) {
 					t.Fatalf("JSON error when rendering %s using %s: %s. Payload: %s", modelName, entry.Name, err, string(result))
 				}
 			}
+
+			if schemas.PlatformSchema(info.Platform) != nil && (!info.SingleEvent || eventCount(t, model) == 1) {
+				if err := ValidateOutput(info.Platform, result); err != nil {
+					t.Errorf("Invalid %s payload when rendering %s using %s: %s", info.Platform, modelName, entry.Name, err)
+				}
+			}
+
+			if info.FanOut {
+				req.FanOut = true
+				for i, event := range Render(req).Events {
+					if event.Error != "" {
+						t.Fatalf("Error rendering event %d of %s using %s: %s", i, modelName, entry.Name, event.Error)
+					}
+					if err := ValidateOutput(info.Platform, event.Output); err != nil {
+						t.Errorf("Invalid %s payload when rendering event %d of %s using %s: %s", info.Platform, i, modelName, entry.Name, err)
+					}
+				}
+			}
 		}
 	}
 
 	t.Logf("Rendered all successfully using %d view models and %d template files", len(TestingViewModels), len(entries))
 }
+
+func eventCount(t *testing.T, model json.RawMessage) int {
+	var vm NotificationViewModel
+	if err := json.Unmarshal(model, &vm); err != nil {
+		t.Fatalf("Error decoding view model: %s", err)
+	}
+	return len(vm.Events())
+}
+
+func Test_RenderFanOut(t *testing.T) {
+	data := json.RawMessage(`{"CompanyID": 1, "CompanyName": "Test", "Events": [
+		{"Type": "insight", "Description": "first"},
+		{"Type": "alarm", "Description": "second"},
+		{"Type": "insight", "Description": "third"}
+	]}`)
+	resp := Render(RenderRequest{
+		Template: `{{ .Event.Description }} {{ len .Events }}/{{ len .Digest.Events }}{{ if .Event.IsAlarm }}{{ .Event.Nope }}{{ end }}`,
+		Data:     data,
+		FanOut:   true,
+	})
+	if resp.Error != "" || resp.Output != "" {
+		t.Fatalf("Expected results in events only, got %+v", resp)
+	}
+	if len(resp.Events) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", resp.Events)
+	}
+	for i, output := range []string{"first 1/3", "", "third 1/3"} {
+		if resp.Events[i].Output != output {
+			t.Errorf("Expected event %d output %q, got %q", i, output, resp.Events[i].Output)
+		}
+	}
+	if resp.Events[1].ErrorKind != ErrorKind_Exec || resp.Events[0].Error != "" {
+		t.Errorf("Expected an exec error for the second event only, got %+v", resp.Events)
+	}
+
+	single := Render(RenderRequest{Template: `{{ len .Digest.Events }}`, Data: data})
+	if single.Output != "3" {
+		t.Errorf("Expected the notification itself as digest, got %q", single.Output)
+	}
+}
+
+func Test_RenderDedupKey(t *testing.T) {
+	tests := []struct {
+		template string
+		output   string
+		error    string
+	}{
+		{template: `{{ dedupKey .Event }}`, output: "1228"},
+		{template: `{{ dedupKey .Event 16 }}`, output: "6dfb97632210ac38"},
+		{template: `{{ dedupKey .Event 65 }}`, error: "dedupKey length must be between 1 and 64"},
+	}
+
+	for _, tt := range tests {
+		resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels["synthetics"]})
+		if resp.Output != tt.output || !strings.Contains(resp.Error, tt.error) || (tt.error == "") != (resp.Error == "") {
+			t.Errorf("Expected %q (error %q) for %s, got %q (error %q)", tt.output, tt.error, tt.template, resp.Output, resp.Error)
+		}
+	}
+}
+
+func Test_RenderSections(t *testing.T) {
+	req := RenderRequest{
+		Template: `{{ define "title" }}[{{ .CompanyName }}] {{ .Headline }}{{ end }}`,
+		Sections: map[string]string{
+			Section_Subject: `{{ template "title" . }}`,
+			Section_Body:    `{{ template "title" . }}: {{ .Summary }}`,
+			Section_Headers: `X-Company-ID: {{ .CompanyID }`,
+		},
+		Data: TestingViewModels["alarm"],
+	}
+
+	resp := Render(req)
+	if resp.Error != "" {
+		t.Fatalf("Unexpected error: %s", resp.Error)
+	}
+	if len(resp.Sections) != 3 {
+		t.Fatalf("Expected 3 sections, got %d", len(resp.Sections))
+	}
+
+	subject := resp.Sections[Section_Subject]
+	if subject.Error != "" || !strings.HasPrefix(subject.Output, "[") || !strings.Contains(subject.Output, "Kentik Alert") {
+		t.Errorf("Unexpected subject: %+v", subject)
+	}
+	body := resp.Sections[Section_Body]
+	if body.Error != "" || !strings.HasPrefix(body.Output, subject.Output+": ") {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+	headers := resp.Sections[Section_Headers]
+	if headers.Error == "" || headers.Line != 1 || !strings.Contains(headers.Error, "headers") {
+		t.Errorf("Expected headers section parse error, got %+v", headers)
+	}
+}
+
+func Test_AllExamples_RenderStrict(t *testing.T) {
+	entries, err := templateFiles("../../templates")
+	if err != nil {
+		t.Fatalf("Error reading directory: %s", err)
+	}
+
+	for _, entry := range entries {
+		templateContent, err := os.ReadFile(entry.Path)
+		if err != nil {
+			t.Fatalf("Error reading template file %s: %s", entry.Name, err)
+		}
+
+		for modelName, model := range TestingViewModels {
+			resp := Render(RenderRequest{
+				Name:     entry.Name,
+				Template: string(templateContent),
+				Data:     model,
+				Strict:   true,
+			})
+			if resp.Error != "" {
+				t.Errorf("Error rendering %s using %s in strict mode: %s", modelName, entry.Name, resp.Error)
+			}
+		}
+	}
+}
+
+func Test_RenderStrict(t *testing.T) {
+	noEvents := json.RawMessage(`{"CompanyID": 1, "CompanyName": "Test", "Events": []}`)
+
+	tests := []struct {
+		name     string
+		req      RenderRequest
+		kind     string
+		contains string
+	}{
+		{
+			name:     "Missing detail",
+			req:      RenderRequest{Template: `{{ .Event.Details.GetValue "AlarmPolicyName" }}`, Data: TestingViewModels["insight"]},
+			kind:     ErrorKind_Exec,
+			contains: `at <.Event.Details.GetValue>: error calling GetValue: detail AlarmPolicyName not present`,
+		},
+		{
+			name: "Tested missing detail",
+			req:  RenderRequest{Template: `{{ with .Event.Details.GetValue "AlarmPolicyName" }}{{ . }}{{ end }}`, Data: TestingViewModels["insight"]},
+		},
+		{
+			name:     "Nil event",
+			req:      RenderRequest{Template: `{{ if .Event }}{{ .Event.Title }}{{ end }}{{ toJSON .Event }}`, Data: noEvents},
+			kind:     ErrorKind_Exec,
+			contains: `at <.Event>: error calling Event: Event is nil`,
+		},
+		{
+			name:     "Missing key",
+			req:      RenderRequest{Template: `{{ .Event.Details.ToMap.Nope }}`, Data: TestingViewModels["alarm"]},
+			kind:     ErrorKind_Exec,
+			contains: `map has no entry for key "Nope"`,
+		},
+		{
+			name:     "Unknown config field",
+			req:      RenderRequest{Template: `{{ .CompanyID }}`, Data: json.RawMessage(`{"CompanyID": 1, "Config": {"BaseDomain": "portal.kentik.com", "Bogus": 1}}`)},
+			kind:     ErrorKind_Data,
+			contains: `json: unknown field "Bogus"`,
+		},
+		{
+			name:     "Unknown detail field",
+			req:      RenderRequest{Template: `{{ .CompanyID }}`, Data: json.RawMessage(`{"CompanyID": 1, "Events": [{"Type": "alarm", "Details": [{"Name": "AlarmID", "Value": 1, "Bogus": 1}]}]}`)},
+			kind:     ErrorKind_Data,
+			contains: `json: unknown field "Bogus"`,
+		},
+		{
+			name:     "Invalid JSON output",
+			req:      RenderRequest{Name: "test.json.tmpl", Template: `{"company": "{{ .CompanyName }}",}`, Data: TestingViewModels["alarm"]},
+			kind:     ErrorKind_Output,
+			contains: "invalid character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lenient := Render(tt.req)
+			if lenient.Error != "" {
+				t.Fatalf("Unexpected error without strict mode: %s", lenient.Error)
+			}
+
+			tt.req.Strict = true
+			resp := Render(tt.req)
+			if resp.ErrorKind != tt.kind {
+				t.Fatalf("Expected error kind %q, got %q: %s", tt.kind, resp.ErrorKind, resp.Error)
+			}
+			if !strings.Contains(resp.Error, tt.contains) {
+				t.Errorf("Error %q does not contain %q", resp.Error, tt.contains)
+			}
+		})
+	}
+}
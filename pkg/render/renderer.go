@@ -0,0 +1,129 @@
+package render
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultRendererCacheSize is the number of compiled templates kept by NewRenderer(0).
+const DefaultRendererCacheSize = 128
+
+// Renderer compiles templates once and keeps them in an LRU cache keyed by
+// a hash of the template content, so repeated renders skip parsing.
+// A Renderer is safe for concurrent use.
+type Renderer struct {
+	mu       sync.Mutex
+	capacity int
+	entries  *list.List
+	index    map[string]*list.Element
+}
+
+type rendererEntry struct {
+	key  string
+	tmpl *parsedTemplate
+	err  error
+}
+
+// NewRenderer returns a Renderer caching up to cacheSize compiled templates.
+// A non-positive size falls back to DefaultRendererCacheSize.
+func NewRenderer(cacheSize int) *Renderer {
+	if cacheSize <= 0 {
+		cacheSize = DefaultRendererCacheSize
+	}
+	return &Renderer{
+		capacity: cacheSize,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+// Render works like the package level Render, reusing a cached compiled template when possible.
+func (r *Renderer) Render(req RenderRequest) RenderResponse {
+	return r.RenderContext(context.Background(), req, RenderOptions{})
+}
+
+// RenderContext works like the package level RenderContext, reusing a cached compiled template when possible.
+func (r *Renderer) RenderContext(ctx context.Context, req RenderRequest, opts RenderOptions) RenderResponse {
+	tmpl, err := r.compile(req)
+	if err != nil {
+		return renderErr(err)
+	}
+	return tmpl.render(ctx, req, opts)
+}
+
+// Len returns the number of compiled templates currently cached.
+func (r *Renderer) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries.Len()
+}
+
+func (r *Renderer) compile(req RenderRequest) (*parsedTemplate, error) {
+	key := templateKey(req)
+
+	r.mu.Lock()
+	if el, ok := r.index[key]; ok {
+		r.entries.MoveToFront(el)
+		entry := el.Value.(*rendererEntry)
+		r.mu.Unlock()
+		return entry.tmpl, entry.err
+	}
+	r.mu.Unlock()
+
+	// parse outside of the lock, concurrent misses of the same key may parse twice
+	tmpl, err := parseTemplate(req)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.index[key]; ok {
+		r.entries.MoveToFront(el)
+		entry := el.Value.(*rendererEntry)
+		return entry.tmpl, entry.err
+	}
+	r.index[key] = r.entries.PushFront(&rendererEntry{key: key, tmpl: tmpl, err: err})
+	for r.entries.Len() > r.capacity {
+		oldest := r.entries.Back()
+		r.entries.Remove(oldest)
+		delete(r.index, oldest.Value.(*rendererEntry).key)
+	}
+	return tmpl, err
+}
+
+// templateKey hashes everything that affects parsing of the request.
+func templateKey(req RenderRequest) string {
+	h := sha256.New()
+	write := func(s string) {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(s)))
+		h.Write(size[:])
+		h.Write([]byte(s))
+	}
+
+	write(req.Name)
+	write(req.Template)
+
+	sections := make([]string, 0, len(req.Sections))
+	for name := range req.Sections {
+		sections = append(sections, name)
+	}
+	sort.Strings(sections)
+	for _, name := range sections {
+		write(name)
+		write(req.Sections[name])
+	}
+
+	write(strconv.FormatBool(req.JSON))
+	jsonSections := append([]string(nil), req.JSONSections...)
+	sort.Strings(jsonSections)
+	for _, name := range jsonSections {
+		write(name)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_Renderer_MatchesRender(t *testing.T) {
+	entries, err := templateFiles("../../templates")
+	if err != nil {
+		t.Fatalf("Error reading directory: %s", err)
+	}
+
+	renderer := NewRenderer(0)
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		templateContent, err := os.ReadFile(entry.Path)
+		if err != nil {
+			t.Fatalf("Error reading template file %s: %s", entry.Name, err)
+		}
+		for modelName, model := range TestingViewModels {
+			req := RenderRequest{Template: string(templateContent), Data: model}
+			expected := Render(req)
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp := renderer.Render(req)
+					if resp.Error != expected.Error || resp.Output != expected.Output {
+						t.Errorf("Renderer output differs for %s using %s", modelName, entry.Name)
+					}
+				}()
+			}
+		}
+	}
+	wg.Wait()
+
+	if renderer.Len() != len(entries) {
+		t.Errorf("Expected %d cached templates, got %d", len(entries), renderer.Len())
+	}
+}
+
+func Test_Renderer_Eviction(t *testing.T) {
+	renderer := NewRenderer(2)
+	data := TestingViewModels["alarm"]
+
+	for i := 0; i < 3; i++ {
+		resp := renderer.Render(RenderRequest{Template: fmt.Sprintf("%d {{ .CompanyID }}", i), Data: data})
+		if resp.Error != "" {
+			t.Fatalf("Unexpected error: %s", resp.Error)
+		}
+	}
+	if renderer.Len() != 2 {
+		t.Errorf("Expected 2 cached templates, got %d", renderer.Len())
+	}
+
+	resp := renderer.Render(RenderRequest{Template: "{{ .Broken", Data: data})
+	if !strings.Contains(resp.Error, "unclosed action") {
+		t.Errorf("Expected parse error, got %q", resp.Error)
+	}
+}
@@ -0,0 +1,399 @@
+package render
+
+import (
+	"sort"
+	"strconv"
+	"text/template"
+	"text/template/parse"
+)
+
+// Names of internal functions injected into parsed templates. They are bound
+// per execution, the versions added to the set after parsing, so that
+// templates cannot call them, are inert fallbacks.
+const (
+	rangeGuardFunc = "_render_rangeguard"
+	detailFunc     = "_render_detail"
+	eventFunc      = "_render_event"
+)
+
+var internalFuncMap = template.FuncMap{
+	rangeGuardFunc: func(vars int, v interface{}) interface{} { return v },
+	detailFunc: func(site int, method string, details EventViewModelDetails, names ...string) interface{} {
+		return callDetails(details, method, names)
+	},
+	eventFunc: func(site int, vm *NotificationViewModel, text string) *EventViewModel { return vm.Event() },
+
+	jsonStringFunc: jsonEscapeString,
+	jsonValueFunc:  jsonEscapeValue,
+	jsonKeyFunc:    jsonEscapeKey,
+}
+
+// instrument rewrites parse trees of all templates in the set so that
+// execution can be observed and limited. It must run once, before the set is
+// first executed. Templates named in sources are analyzed as executed with
+// the view model, places which may produce warnings are returned as sites.
+// Nodes the rewriting creates are recorded in origins, unless nil.
+func instrument(tmpl *template.Template, sources map[string]string, origins nodeOrigins) []warningSite {
+	templates := tmpl.Templates()
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name() < templates[j].Name() })
+
+	trees := make(map[string]*parse.Tree)
+	for _, t := range templates {
+		if t.Tree != nil && t.Tree.Root != nil {
+			trees[t.Name()] = t.Tree
+		}
+	}
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := &warningRewriter{uses: analyzeChains(trees, names), sources: sources, origins: origins}
+	seen := make(map[*parse.Tree]bool)
+	for _, t := range templates {
+		if t.Tree == nil || t.Tree.Root == nil || seen[t.Tree] {
+			continue
+		}
+		seen[t.Tree] = true
+		w.rewrite(t.Tree)
+		guardRanges(t.Tree, origins)
+	}
+	return w.sites
+}
+
+// guardRanges pipes the value of every range action through rangeGuardFunc,
+// along with the number of variables the range declares. Errors about the
+// value ranged over quote the guard, which stands for the last command.
+func guardRanges(tree *parse.Tree, origins nodeOrigins) {
+	walkTree(tree.Root, func(node parse.Node) {
+		r, ok := node.(*parse.RangeNode)
+		if !ok {
+			return
+		}
+		pos := r.Pipe.Position()
+		vars := siteNumber(len(r.Pipe.Decl), pos)
+		guard := funcCommand(rangeGuardFunc, pos, vars)
+		last := r.Pipe.Cmds[len(r.Pipe.Cmds)-1]
+		for _, n := range []parse.Node{vars, guard, guard.Args[0]} {
+			origins.derive(tree, n, last)
+		}
+		r.Pipe.Cmds = append(r.Pipe.Cmds, guard)
+	})
+}
+
+// warningSite is a rewritten place in the template source which may produce warnings.
+type warningSite struct {
+	name   string // template or section the range refers to
+	rng    Range
+	text   string // the rewritten chain as written in the template
+	method string // the method called at the end of the chain
+	tested bool   // the result is only tested, see testedOperands
+}
+
+// warningRewriter routes detail lookups on EventViewModelDetails through
+// detailFunc and NotificationViewModel.Event calls through eventFunc, so
+// that their results can be checked during execution.
+type warningRewriter struct {
+	uses    map[parse.Node]chainUse
+	sources map[string]string
+	sites   []warningSite
+	origins nodeOrigins
+}
+
+func (w *warningRewriter) rewrite(tree *parse.Tree) {
+	tested := testedOperands(tree.Root)
+	walkTree(tree.Root, func(node parse.Node) {
+		if cmd, ok := node.(*parse.CommandNode); ok {
+			w.command(tree, cmd, tested)
+		}
+	})
+}
+
+func (w *warningRewriter) command(tree *parse.Tree, cmd *parse.CommandNode, tested map[parse.Node]bool) {
+	args := make([]parse.Node, 0, len(cmd.Args)+3)
+	for i, arg := range cmd.Args {
+		use, ok := w.uses[arg]
+		if !ok || use.mixed {
+			args = append(args, arg)
+			continue
+		}
+
+		base, names := chainParts(arg)
+		switch {
+		case i == 0 && use.details:
+			method := names[len(names)-1]
+			start := chainStart(arg, names, len(names)-1)
+			end := start + len(method) + 1
+			for _, a := range cmd.Args[1:] {
+				if str, ok := a.(*parse.StringNode); ok {
+					end = int(str.Pos) + len(str.Quoted)
+				}
+			}
+			site := w.site(tree, start, end, arg.String(), method)
+			w.sites[site].tested = tested[arg]
+			recv := w.chain(tree, arg, base, names[:len(names)-1], use.event)
+			w.origins.derive(tree, recv, buildChain(base, names[:len(names)-1], arg.Position()))
+			args = append(args,
+				parse.NewIdentifier(detailFunc).SetPos(arg.Position()),
+				siteNumber(site, arg.Position()),
+				&parse.StringNode{NodeType: parse.NodeString, Pos: arg.Position(), Quoted: strconv.Quote(method), Text: method},
+				recv)
+		case use.event >= 0:
+			// a bare Event which is only tested or has arguments is left alone
+			if use.event == len(names)-1 && (tested[arg] && len(cmd.Args) == 1 || i == 0 && len(cmd.Args) > 1) {
+				args = append(args, arg)
+				continue
+			}
+			chain := w.chain(tree, arg, base, names, use.event)
+			w.origins.derive(tree, chain, arg)
+			args = append(args, chain)
+		default:
+			args = append(args, arg)
+		}
+	}
+	cmd.Args = args
+}
+
+// chain rebuilds the chain of names on base, calling eventFunc in place of
+// the name at index event, if any. The call ends with the original text of
+// the chain: text/template reports errors in the rest of the chain at the
+// last node it evaluated.
+func (w *warningRewriter) chain(tree *parse.Tree, orig, base parse.Node, names []string, event int) parse.Node {
+	pos := orig.Position()
+	if event < 0 || event >= len(names) {
+		return buildChain(base, names, pos)
+	}
+
+	start := chainStart(orig, names, event)
+	site := w.site(tree, start, start+len(names[event])+1, orig.String(), names[event])
+	text := buildChain(base, names, pos).String()
+	call := &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      pos,
+		Cmds: []*parse.CommandNode{funcCommand(eventFunc, pos,
+			siteNumber(site, pos),
+			buildChain(base, names[:event], pos),
+			&parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: text, Text: text})},
+	}
+	return buildChain(call, names[event+1:], pos)
+}
+
+func (w *warningRewriter) site(tree *parse.Tree, start, end int, text, method string) int {
+	w.sites = append(w.sites, warningSite{
+		name:   tree.ParseName,
+		rng:    rangeOf(w.sources[tree.ParseName], start, end),
+		text:   text,
+		method: method,
+	})
+	return len(w.sites) - 1
+}
+
+// nodeOrigins keeps the text of nodes as parsed, so that execution errors
+// quote the template and not its rewritten form. It holds the nodes which
+// rewriting may change, and the nodes it creates in place of parsed ones.
+type nodeOrigins map[parse.Node]nodeOrigin
+
+type nodeOrigin struct {
+	name string // template or section the node was parsed from
+	text string
+}
+
+// contextKey identifies a node quoted by an execution error: text/template
+// gives its position and its text as rewritten.
+type contextKey struct {
+	name string
+	pos  int
+	text string
+}
+
+// snapshot records the text of nodes of all templates in the set which
+// contain other nodes, before any of them is rewritten.
+func (o nodeOrigins) snapshot(tmpl *template.Template) {
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		walkTree(t.Tree.Root, func(node parse.Node) {
+			switch node.(type) {
+			case *parse.ActionNode, *parse.PipeNode, *parse.CommandNode, *parse.ChainNode, *parse.TemplateNode:
+				o[node] = nodeOrigin{name: t.Tree.ParseName, text: node.String()}
+			}
+		})
+	}
+}
+
+// derive records a node created in place of the parsed node from. Nothing
+// is recorded in nil origins.
+func (o nodeOrigins) derive(tree *parse.Tree, node, from parse.Node) {
+	if o == nil {
+		return
+	}
+	origin, ok := o[from]
+	if !ok {
+		origin = nodeOrigin{name: tree.ParseName, text: from.String()}
+	}
+	o[node] = origin
+}
+
+// contexts maps the nodes changed by rewriting, as execution errors quote
+// them, to their text as parsed. It must be called once rewriting is done.
+func (o nodeOrigins) contexts() map[contextKey]string {
+	res := make(map[contextKey]string)
+	for node, origin := range o {
+		if text := node.String(); text != origin.text {
+			res[contextKey{name: origin.name, pos: int(node.Position()), text: text}] = origin.text
+		}
+	}
+	return res
+}
+
+// testedOperands returns operands whose value is only tested or stored: the
+// operand of the sole command of if, with and declarations, and arguments of
+// and, or and not.
+func testedOperands(root parse.Node) map[parse.Node]bool {
+	res := make(map[parse.Node]bool)
+	sole := func(pipe *parse.PipeNode) {
+		if len(pipe.Cmds) == 1 {
+			res[pipe.Cmds[0].Args[0]] = true
+		}
+	}
+	walkTree(root, func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.IfNode:
+			sole(n.Pipe)
+		case *parse.WithNode:
+			sole(n.Pipe)
+		case *parse.PipeNode:
+			if len(n.Decl) > 0 {
+				sole(n)
+			}
+		case *parse.CommandNode:
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok {
+				switch ident.Ident {
+				case "and", "or", "not":
+					for _, arg := range n.Args[1:] {
+						if pipe, ok := arg.(*parse.PipeNode); ok {
+							sole(pipe)
+						}
+						res[arg] = true
+					}
+				}
+			}
+		}
+	})
+	return res
+}
+
+// chainParts splits a field, variable or chain node into the value its names
+// are evaluated on and the names. Field chains start on dot.
+func chainParts(node parse.Node) (parse.Node, []string) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return &parse.DotNode{NodeType: parse.NodeDot, Pos: n.Pos}, n.Ident
+	case *parse.VariableNode:
+		return &parse.VariableNode{NodeType: parse.NodeVariable, Pos: n.Pos, Ident: n.Ident[:1]}, n.Ident[1:]
+	case *parse.ChainNode:
+		return n.Node, n.Field
+	}
+	return node, nil
+}
+
+// buildChain is the inverse of chainParts.
+func buildChain(base parse.Node, names []string, pos parse.Pos) parse.Node {
+	if len(names) == 0 {
+		return base
+	}
+	names = append([]string{}, names...)
+	switch n := base.(type) {
+	case *parse.DotNode:
+		return &parse.FieldNode{NodeType: parse.NodeField, Pos: pos, Ident: names}
+	case *parse.VariableNode:
+		return &parse.VariableNode{NodeType: parse.NodeVariable, Pos: pos, Ident: append([]string{n.Ident[0]}, names...)}
+	}
+	return &parse.ChainNode{NodeType: parse.NodeChain, Pos: pos, Node: base, Field: names}
+}
+
+// chainStart returns the source offset of the name at index i of a chain split by chainParts.
+func chainStart(node parse.Node, names []string, i int) int {
+	var start int
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		start = fieldStart(n)
+	case *parse.VariableNode:
+		start = variableStart(n) + len(n.Ident[0])
+	default:
+		start = int(node.Position())
+	}
+	for _, name := range names[:i] {
+		start += len(name) + 1
+	}
+	return start
+}
+
+func siteNumber(site int, pos parse.Pos) *parse.NumberNode {
+	return &parse.NumberNode{
+		NodeType: parse.NodeNumber,
+		Pos:      pos,
+		IsInt:    true,
+		Int64:    int64(site),
+		Text:     strconv.Itoa(site),
+	}
+}
+
+// funcCommand builds a command node calling the named function.
+func funcCommand(name string, pos parse.Pos, args ...parse.Node) *parse.CommandNode {
+	ident := parse.NewIdentifier(name).SetPos(pos)
+	return &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      pos,
+		Args:     append([]parse.Node{ident}, args...),
+	}
+}
+
+// walkTree calls visit for every node reachable from node, parents first.
+func walkTree(node parse.Node, visit func(parse.Node)) {
+	if node == nil {
+		return
+	}
+	visit(node)
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			walkTree(child, visit)
+		}
+	case *parse.ActionNode:
+		walkTree(n.Pipe, visit)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, visit)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, visit)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, visit)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			walkTree(n.Pipe, visit)
+		}
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			walkTree(cmd, visit)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkTree(arg, visit)
+		}
+	case *parse.ChainNode:
+		walkTree(n.Node, visit)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, visit func(parse.Node)) {
+	walkTree(n.Pipe, visit)
+	if n.List != nil {
+		walkTree(n.List, visit)
+	}
+	if n.ElseList != nil {
+		walkTree(n.ElseList, visit)
+	}
+}
@@ -203,7 +203,8 @@ func extractTypeMethods(t reflect.Type, path string) []*SchemaField {
 			}
 
 			// For struct return types, include their fields and methods as children
-			if returnType.Kind() == reflect.Struct && !isBasicType(returnType) {
+			// A method returning its own type, like Digest, is not expanded again
+			if returnType.Kind() == reflect.Struct && !isBasicType(returnType) && returnType != t.Elem() {
 				childPath := path
 				if childPath == "." {
 					childPath = "." + method.Name
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretMask replaces secret values in previews.
+const secretMask = "********"
+
+// tokenPatterns match credentials which should be passed as secret params
+// instead of being written into templates or shown in previews. Patterns
+// with a group match the token in its context, e.g. after the field name.
+var tokenPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}`)},
+	{"Slack webhook URL", regexp.MustCompile(`hooks\.slack\.com/services/T[0-9A-Z]+/B[0-9A-Z]+/[0-9A-Za-z]+`)},
+	{"Discord webhook URL", regexp.MustCompile(`discord(?:app)?\.com/api/webhooks/[0-9]+/[0-9A-Za-z_-]+`)},
+	{"Telegram bot token", regexp.MustCompile(`\b[0-9]{8,10}:[0-9A-Za-z_-]{35}\b`)},
+	{"AWS access key", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36}\b`)},
+	{"JSON Web Token", regexp.MustCompile(`\beyJ[0-9A-Za-z_-]+\.eyJ[0-9A-Za-z_-]+\.[0-9A-Za-z_-]+`)},
+	{"PagerDuty integration key", regexp.MustCompile(`(?:routing|integration)_key\W{1,8}([0-9a-f]{32})\b`)},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+[0-9A-Za-z._~+/-]{20,}=*`)},
+}
+
+// tokenMatch is a token found in a text, start and end are byte offsets.
+type tokenMatch struct {
+	kind       string
+	start, end int
+}
+
+// findTokens finds anything in text shaped like a credential.
+func findTokens(text string) []tokenMatch {
+	var res []tokenMatch
+	for _, pattern := range tokenPatterns {
+		for _, loc := range pattern.re.FindAllStringSubmatchIndex(text, -1) {
+			if len(loc) > 2 {
+				loc = loc[2:]
+			}
+			res = append(res, tokenMatch{kind: pattern.kind, start: loc[0], end: loc[1]})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].start < res[j].start })
+	return res
+}
+
+func tokenMessage(kind string) string {
+	return fmt.Sprintf("template inlines what looks like a %s, pass it as a secret param instead", kind)
+}
+
+// tokenWarnings reports tokens inlined in the sources of a template.
+func tokenWarnings(sources map[string]string) []Warning {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var res []Warning
+	for _, name := range names {
+		text := sources[name]
+		for _, token := range findTokens(text) {
+			res = append(res, Warning{
+				Message:      tokenMessage(token.kind),
+				Pane:         Pane_Template,
+				TemplateName: name,
+				Range:        rangeOf(text, token.start, token.end),
+			})
+		}
+	}
+	return res
+}
+
+// inlinedTokens returns warnings about tokens inlined in the sources of the template.
+func (t *parsedTemplate) inlinedTokens() []Warning {
+	t.tokensOnce.Do(func() {
+		t.tokens = tokenWarnings(t.sources)
+	})
+	return t.tokens
+}
+
+// secretValues returns the values of secret params in the textual forms they
+// can take in output, longest first.
+func (t *parsedTemplate) secretValues(params map[string]interface{}) []string {
+	var res []string
+	for _, spec := range t.params {
+		value, ok := params[spec.Name]
+		if !spec.Secret || !ok {
+			continue
+		}
+		if text := fmt.Sprint(value); text != "" {
+			quoted := jsonQuote(text)
+			res = append(res, text, quoted[1:len(quoted)-1])
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return len(res[i]) > len(res[j]) })
+	return res
+}
+
+// redact masks secret values and anything shaped like a token in output.
+func redact(output string, secrets []string) string {
+	for _, secret := range secrets {
+		output = maskSecret(output, secret)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, token := range findTokens(output) {
+		// tokens matched by several patterns overlap
+		if token.start < last {
+			last = max(last, token.end)
+			continue
+		}
+		b.WriteString(output[last:token.start])
+		b.WriteString(secretMask)
+		last = token.end
+	}
+	b.WriteString(output[last:])
+	return b.String()
+}
+
+// maskSecret masks occurrences of secret in text which are not part of
+// a longer word, so that a short secret such as 1 is kept in id=1002.
+func maskSecret(text, secret string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i <= len(text)-len(secret); {
+		j := strings.Index(text[i:], secret)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(secret)
+		if !wordBoundary(text, start) || !wordBoundary(text, end) {
+			i = start + 1
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(secretMask)
+		last, i = end, end
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// wordBoundary tells whether text can be split at i without splitting
+// a word, like \b of regular expressions.
+func wordBoundary(text string, i int) bool {
+	return i == 0 || i == len(text) || !isWordByte(text[i-1]) || !isWordByte(text[i])
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isAlphaNumeric(c)
+}
+
+// setPreview fills Preview of the response and its sections.
+func (resp *RenderResponse) setPreview(secrets []string) {
+	resp.Preview = redact(resp.Output, secrets)
+	for name, section := range resp.Sections {
+		section.setPreview(secrets)
+		resp.Sections[name] = section
+	}
+}
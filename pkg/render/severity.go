@@ -0,0 +1,151 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SeverityMappings maps importance levels, by their ImportanceNames, to the
+// severity vocabulary of each destination platform.
+var SeverityMappings = map[string]map[string]string{
+	// PagerDuty payload.severity
+	"pagerduty": {
+		"critical": "critical",
+		"severe":   "error",
+		"major":    "error",
+		"warning":  "warning",
+		"minor":    "info",
+		"notice":   "info",
+		"healthy":  "info",
+		"n/a":      "info",
+	},
+	// Opsgenie alert priority
+	"opsgenie": {
+		"critical": "P1",
+		"severe":   "P2",
+		"major":    "P2",
+		"warning":  "P3",
+		"minor":    "P4",
+		"notice":   "P5",
+		"healthy":  "P5",
+		"n/a":      "P5",
+	},
+	// ServiceNow event severity: 1 critical to 5 info, 0 clear
+	"servicenow": {
+		"critical": "1",
+		"severe":   "2",
+		"major":    "3",
+		"warning":  "4",
+		"minor":    "4",
+		"notice":   "5",
+		"healthy":  "0",
+		"n/a":      "5",
+	},
+	// Splunk On-Call (VictorOps) message_type
+	"splunk-oncall": {
+		"critical": "CRITICAL",
+		"severe":   "CRITICAL",
+		"major":    "CRITICAL",
+		"warning":  "WARNING",
+		"minor":    "WARNING",
+		"notice":   "INFO",
+		"healthy":  "RECOVERY",
+		"n/a":      "INFO",
+	},
+	// syslog severity (RFC 5424): 2 critical to 6 informational
+	"syslog": {
+		"critical": "2",
+		"severe":   "3",
+		"major":    "3",
+		"warning":  "4",
+		"minor":    "5",
+		"notice":   "5",
+		"healthy":  "6",
+		"n/a":      "6",
+	},
+	// CEF severity: 0 to 10
+	"cef": {
+		"critical": "10",
+		"severe":   "9",
+		"major":    "7",
+		"warning":  "5",
+		"minor":    "4",
+		"notice":   "2",
+		"healthy":  "0",
+		"n/a":      "0",
+	},
+}
+
+// severityLevel normalizes an importance, or an AlarmSeverity detail value,
+// to a key of SeverityMappings. Unknown severities are n/a.
+func severityLevel(severity interface{}) string {
+	var importance ViewModelImportance
+	switch v := severity.(type) {
+	case ViewModelImportance:
+		importance = v
+	case int:
+		importance = ViewModelImportance(v)
+	case float64:
+		importance = ViewModelImportance(v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return ImportanceNames[ViewModelImportance_None]
+		}
+		importance = ViewModelImportance(n)
+	case string:
+		if level, ok := knownSeverityLevel(v); ok {
+			return level
+		}
+	}
+	if name, ok := ImportanceNames[importance]; ok {
+		return name
+	}
+	return ImportanceNames[ViewModelImportance_None]
+}
+
+// knownSeverityLevel normalizes an importance name or AlarmSeverity value.
+func knownSeverityLevel(name string) (string, bool) {
+	level := strings.ToLower(name)
+	if level == "clear" {
+		return ImportanceNames[ViewModelImportance_Healthy], true
+	}
+	_, ok := SeverityMappings["pagerduty"][level]
+	return level, ok
+}
+
+// severityOverrides parses overrides of mapSeverity: a map, e.g. a template
+// param, or a string of comma-separated level=severity pairs.
+func severityOverrides(overrides interface{}) (map[string]string, error) {
+	res := make(map[string]string)
+	switch v := overrides.(type) {
+	case nil:
+	case map[string]interface{}:
+		for name, severity := range v {
+			level, ok := knownSeverityLevel(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown severity level %q", name)
+			}
+			res[level] = fmt.Sprint(severity)
+		}
+	case string:
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			name, severity, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid severity override %q, expected level=severity", strings.TrimSpace(pair))
+			}
+			level, ok := knownSeverityLevel(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("unknown severity level %q", strings.TrimSpace(name))
+			}
+			res[level] = strings.TrimSpace(severity)
+		}
+	default:
+		return nil, fmt.Errorf("severity overrides must be a map or a string, got %T", overrides)
+	}
+	return res, nil
+}
@@ -0,0 +1,48 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_RenderMapSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]interface{}
+		output   string
+		error    string
+	}{
+		{name: "AlarmSeverity", template: `{{ mapSeverity "pagerduty" "severe" }} {{ mapSeverity "opsgenie" "Critical" }} {{ mapSeverity "servicenow" "clear" }}`, output: "error P1 0"},
+		{name: "Importance", template: `{{ mapSeverity "syslog" .Event.Importance }} {{ mapSeverity "cef" 4 }}`, output: "3 5"},
+		{name: "Unknown severity", template: `{{ mapSeverity "splunk-oncall" "bogus" }}`, output: "INFO"},
+		{name: "Overrides", template: `{{ mapSeverity "opsgenie" "minor" "minor=P3" }} {{ mapSeverity "opsgenie" "major" "minor=P3" }}`, output: "P3 P2"},
+		{name: "Param overrides", template: `{{ mapSeverity "opsgenie" "minor" "minor=P3" .Params.severities }}`, params: map[string]interface{}{"severities": "minor=P5, major=P1"}, output: "P5"},
+		{name: "Map overrides", template: `{{ mapSeverity "cef" "clear" .Params.severities }}`, params: map[string]interface{}{"severities": map[string]interface{}{"healthy": 1}}, output: "1"},
+		{name: "Unknown platform", template: `{{ mapSeverity "jira" "minor" }}`, error: `unknown severity platform "jira"`},
+		{name: "Unknown override level", template: `{{ mapSeverity "cef" "minor" "minr=3" }}`, error: `unknown severity level "minr"`},
+		{name: "Invalid override", template: `{{ mapSeverity "cef" "minor" "minor" }}`, error: `invalid severity override "minor", expected level=severity`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels["alarm"], Params: tt.params})
+			if resp.Output != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, resp.Output)
+			}
+			if !strings.Contains(resp.Error, tt.error) || (tt.error == "") != (resp.Error == "") {
+				t.Errorf("Expected error %q, got %q", tt.error, resp.Error)
+			}
+		})
+	}
+}
+
+func Test_SeverityMappings(t *testing.T) {
+	for platform, mapping := range SeverityMappings {
+		for _, level := range ImportanceNames {
+			if mapping[level] == "" {
+				t.Errorf("Missing %s severity for %s", platform, level)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"testing"
+)
+
+func Test_RenderSkip(t *testing.T) {
+	template := `{{ if .IsInsightsOnly }}{{ skip "insights are not paged" }}{{ end }}{{ .Headline }}`
+
+	tests := []struct {
+		name    string
+		data    string
+		strict  bool
+		skipped bool
+	}{
+		{name: "Skipped", data: "insight", skipped: true},
+		{name: "Skipped in strict mode", data: "insight", strict: true, skipped: true},
+		{name: "Not skipped", data: "alarm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: template, Name: "test.json.tmpl", Data: TestingViewModels[tt.data], Strict: tt.strict})
+			if resp.Error != "" {
+				t.Fatalf("Expected no error, got %q", resp.Error)
+			}
+			if resp.Skipped != tt.skipped || (resp.Output == "") != tt.skipped {
+				t.Errorf("Expected skipped %v, got %+v", tt.skipped, resp)
+			}
+			if tt.skipped && resp.SkipReason != "insights are not paged" {
+				t.Errorf("Expected skip reason, got %q", resp.SkipReason)
+			}
+		})
+	}
+}
+
+func Test_RenderSkip_Sections(t *testing.T) {
+	resp := Render(RenderRequest{
+		Template: `{{ .Headline }}`,
+		Sections: map[string]string{Section_Body: `{{ .Headline }}`, Section_Headers: `{{ skip "no headers" }}`},
+		Data:     TestingViewModels["alarm"],
+	})
+	if !resp.Skipped || resp.SkipReason != "no headers" || !resp.Sections[Section_Headers].Skipped {
+		t.Errorf("Expected skipped notification, got %+v", resp)
+	}
+	if len(resp.Sections) != 1 {
+		t.Errorf("Expected no output of other sections of a skipped notification, got %+v", resp.Sections)
+	}
+}
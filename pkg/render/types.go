@@ -1,9 +1,10 @@
 package render
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -41,41 +42,26 @@ var ImportanceNames = map[ViewModelImportance]string{
 	ViewModelImportance_Critical: "critical",
 }
 
-var ImportanceToColors = map[ViewModelImportance]string{
-	ViewModelImportance_None:     "#999999",
-	ViewModelImportance_Healthy:  "#1E9E1E",
-	ViewModelImportance_Notice:   "#157FF3",
-	ViewModelImportance_Minor:    "#F29D49",
-	ViewModelImportance_Warning:  "#EE7E0F",
-	ViewModelImportance_Major:    "#DB3737",
-	ViewModelImportance_Severe:   "#C23030",
-	ViewModelImportance_Critical: "#A82A2A",
-}
+// ImportanceToColors maps importance levels to hex colors of DefaultPalette.
+var ImportanceToColors = DefaultPalette.colors()
 
-var ImportanceToEmojis = map[ViewModelImportance]string{
-	ViewModelImportance_None:     "",
-	ViewModelImportance_Healthy:  ":warning: :large_green_circle:",
-	ViewModelImportance_Notice:   ":warning: :large_blue_circle:",
-	ViewModelImportance_Minor:    ":warning: :large_purple_circle:",
-	ViewModelImportance_Warning:  ":warning: :large_brown_circle:",
-	ViewModelImportance_Major:    ":warning: :large_yellow_circle:",
-	ViewModelImportance_Severe:   ":warning: :large_orange_circle: ",
-	ViewModelImportance_Critical: ":warning: :red_circle:",
-}
+// ImportanceToEmojis maps importance levels to Slack emoji shortcodes of DefaultPalette.
+var ImportanceToEmojis = DefaultPalette.emojis()
 
 type EventViewModel struct {
-	Type           string                `description:"Event type (alarm, insight, synthetic, mitigation, generic)"`
-	Description    string                `json:",omitempty" description:"Human-readable event description"`
-	IsActive       bool                  `description:"Whether the event is currently active"`
-	StartTime      string                `description:"Formatted start time string"`
-	EndTime        string                `description:"Formatted end time string"`
-	CurrentState   string                `description:"Current state of the event"`
-	PreviousState  string                `description:"Previous state of the event"`
-	StartTimestamp int64                 `json:"-" description:"Unix timestamp of event start"`
-	EndTimestamp   int64                 `json:"-" description:"Unix timestamp of event end"`
-	Importance     ViewModelImportance   `json:"-" description:"Severity level (0-7)"`
-	GroupName      string                `json:"-" description:"Name of the event group"`
-	Details        EventViewModelDetails `json:"-" description:"List of event detail key-value pairs"`
+	Type           string                 `description:"Event type (alarm, insight, synthetic, mitigation, generic)"`
+	Description    string                 `json:",omitempty" description:"Human-readable event description"`
+	IsActive       bool                   `description:"Whether the event is currently active"`
+	StartTime      string                 `description:"Formatted start time string"`
+	EndTime        string                 `description:"Formatted end time string"`
+	CurrentState   string                 `description:"Current state of the event"`
+	PreviousState  string                 `description:"Previous state of the event"`
+	StartTimestamp int64                  `json:"-" description:"Unix timestamp of event start"`
+	EndTimestamp   int64                  `json:"-" description:"Unix timestamp of event end"`
+	Importance     ViewModelImportance    `json:"-" description:"Severity level (0-7)"`
+	GroupName      string                 `json:"-" description:"Name of the event group"`
+	Details        EventViewModelDetails  `json:"-" description:"List of event detail key-value pairs"`
+	Extra          map[string]interface{} `json:"-" description:"Payload fields unknown to this renderer version"`
 }
 
 func (e *EventViewModel) UnmarshalJSON(data []byte) error {
@@ -93,12 +79,17 @@ func (e *EventViewModel) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
+	extra, err := unknownFields(data, aux)
+	if err != nil {
+		return err
+	}
 
 	e.StartTimestamp = aux.StartTimestamp
 	e.EndTimestamp = aux.EndTimestamp
 	e.Importance = aux.Importance
 	e.GroupName = aux.GroupName
 	e.Details = aux.Details
+	e.Extra = extra
 	return nil
 }
 
@@ -127,6 +118,44 @@ func (event EventViewModel) IsSynthetic() bool {
 	return event.Type == EventType_Synthetics
 }
 
+// DedupKeyDetails lists, per event type, the details which identify an event
+// across its state changes, in the order they make up its DedupKey.
+var DedupKeyDetails = map[string][]string{
+	EventType_Alarm:         {"AlarmPolicyID", "AlarmID", "AlarmThresholdID"},
+	EventType_Mitigation:    {"MitigationPolicyID", "MitigationID", "MitigationMethodID"},
+	EventType_Insight:       {"InsightID"},
+	EventType_CustomInsight: {"InsightID"},
+	EventType_Synthetics:    {"TestID", "OriginAgentId"},
+}
+
+// DedupKey returns a stable identity of the event, the same for all of its state changes:
+// values of DedupKeyDetails joined by dots, leaving out missing ones, or an empty string
+// for other event types.
+func (event EventViewModel) DedupKey() string {
+	names, ok := DedupKeyDetails[event.Type]
+	if !ok {
+		return ""
+	}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if part := detailString(event.Details.GetValue(name)); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// detailString formats a detail value as part of an identifier, without exponents.
+func detailString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
 // DetailTag categorizes event details.
 type DetailTag string
 
@@ -141,12 +170,15 @@ const (
 )
 
 type EventViewModelDetail struct {
-	Name  string      `description:"Detail field name/key"`
-	Label string      `json:",omitempty" description:"Human-readable label for the detail"`
-	Value interface{} `description:"Detail value (can be any type)"`
-	Tag   DetailTag   `json:"-" description:"Categorization tag (metric, dimension, url, device, etc.)"`
+	Name  string                 `description:"Detail field name/key"`
+	Label string                 `json:",omitempty" description:"Human-readable label for the detail"`
+	Value interface{}            `description:"Detail value (can be any type)"`
+	Tag   DetailTag              `json:"-" description:"Categorization tag (metric, dimension, url, device, etc.)"`
+	Extra map[string]interface{} `json:"-" description:"Payload fields unknown to this renderer version"`
 }
 
+// UnmarshalJSON keeps numbers in Value as json.Number, so that large IDs and
+// counters are printed exactly and not in exponent form.
 func (d *EventViewModelDetail) UnmarshalJSON(data []byte) error {
 	type EvmDetailAsInput EventViewModelDetail
 	aux := &struct {
@@ -155,10 +187,18 @@ func (d *EventViewModelDetail) UnmarshalJSON(data []byte) error {
 	}{
 		EvmDetailAsInput: (*EvmDetailAsInput)(d),
 	}
-	if err := json.Unmarshal(data, &aux); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&aux); err != nil {
 		return err
 	}
+	extra, err := unknownFields(data, aux)
+	if err != nil {
+		return err
+	}
+
 	d.Tag = aux.Tag
+	d.Extra = extra
 	return nil
 }
 
@@ -273,6 +313,11 @@ func (details EventViewModelDetails) GetValue(name string) interface{} {
 	return details.Get(name).Value
 }
 
+// Unit returns the unit of a metric detail value, one of Unit_*, or an empty string if not known.
+func (detail EventViewModelDetail) Unit() string {
+	return MetricUnits[detail.Name]
+}
+
 // LabelOrName returns Label if set, otherwise returns Name.
 func (detail EventViewModelDetail) LabelOrName() string {
 	if detail.Label != "" {
@@ -287,6 +332,10 @@ type NotificationViewModel struct {
 	Now         time.Time               `json:"-" description:"Current timestamp when notification is generated"`
 	RawEvents   []*EventViewModel       `json:"-" description:"List of all events in this notification"`
 	Config      *NotificationViewConfig `json:"-" description:"Notification configuration settings"`
+	Extra       map[string]interface{}  `json:"-" description:"Payload fields unknown to this renderer version"`
+	Params      map[string]interface{}  `json:"-" description:"Template parameters passed alongside the payload"`
+
+	digest *NotificationViewModel
 }
 
 func (vm *NotificationViewModel) UnmarshalJSON(data []byte) error {
@@ -303,17 +352,38 @@ func (vm *NotificationViewModel) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
+	extra, err := unknownFields(data, aux)
+	if err != nil {
+		return err
+	}
 
 	vm.CompanyName = aux.CompanyName
 	vm.Now = aux.Now
 	vm.RawEvents = aux.RawEvents
 	vm.Config = aux.Config
+	vm.Extra = extra
 	return nil
 }
 
 type NotificationViewConfig struct {
-	BaseDomain string   `description:"Portal base domain (e.g., portal.kentik.com)"`
-	EmailTo    []string `description:"List of email recipients"`
+	BaseDomain string                 `description:"Portal base domain (e.g., portal.kentik.com)"`
+	EmailTo    []string               `description:"List of email recipients"`
+	Extra      map[string]interface{} `json:"-" description:"Payload fields unknown to this renderer version"`
+}
+
+func (c *NotificationViewConfig) UnmarshalJSON(data []byte) error {
+	type ConfigAsInput NotificationViewConfig
+	aux := (*ConfigAsInput)(c)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, aux)
+	if err != nil {
+		return err
+	}
+
+	c.Extra = extra
+	return nil
 }
 
 // BasePortalURL returns the portal base URL (without path).
@@ -379,6 +449,22 @@ func (vm *NotificationViewModel) Event() *EventViewModel {
 	return vm.RawEvents[0]
 }
 
+// Digest returns the whole notification when each of its events is rendered separately (fan-out), otherwise the notification itself.
+func (vm *NotificationViewModel) Digest() *NotificationViewModel {
+	if vm.digest != nil {
+		return vm.digest
+	}
+	return vm
+}
+
+// forEvent narrows the notification down to one of its events, for fan-out rendering.
+func (vm *NotificationViewModel) forEvent(event *EventViewModel) *NotificationViewModel {
+	res := *vm
+	res.RawEvents = []*EventViewModel{event}
+	res.digest = vm
+	return &res
+}
+
 // Events returns all events as a slice.
 func (vm *NotificationViewModel) Events() []*EventViewModel {
 	return vm.RawEvents
@@ -475,7 +561,7 @@ func (vm *NotificationViewModel) Summary() string {
 	return strings.Join(segments, ", ")
 }
 
-// PrettifiedMetrics returns metric details with formatted values.
+// PrettifiedMetrics returns metric details with formatted values, labelled with their unit if known (see MetricUnits).
 func (details EventViewModelDetails) PrettifiedMetrics() EventViewModelDetails {
 	result := make(EventViewModelDetails, 0)
 	for _, detail := range details {
@@ -489,19 +575,9 @@ func (details EventViewModelDetails) PrettifiedMetrics() EventViewModelDetails {
 			continue
 		}
 
-		label := detail.Label
-
-		// format bits with proper unit
-		if strings.HasPrefix(detail.Name, "bits") {
-			var prefix string
-			floatValue, prefix = formatBits(floatValue)
-			label = fmt.Sprintf("%sbits/s", prefix)
-		}
-
-		// prevent showing fractions when unnecessary
-		stringValue := fmt.Sprintf("%.2f", floatValue)
-		if _, fraction := math.Modf(floatValue); fraction < 0.05 {
-			stringValue = fmt.Sprintf("%.0f", floatValue)
+		stringValue, label := formatValue(floatValue, detail.Unit(), UnitSystem_SI)
+		if label == "" {
+			label = detail.Label
 		}
 
 		formatted := &EventViewModelDetail{
@@ -522,21 +598,11 @@ func toFloat(value interface{}) (float64, error) {
 		return v, nil
 	case int:
 		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
 	default:
 		return 0, fmt.Errorf("don't know how to convert %T to float64", v)
 	}
 }
-
-func formatBits(bits float64) (float64, string) {
-	const unit = 1024
-	const suffixes = "KMGTPE"
-
-	exp := math.Floor(math.Log(bits) / math.Log(unit))
-	suffix := ""
-	if exp > 0 {
-		suffix = string(suffixes[int(exp)-1])
-	}
-	value := bits / math.Pow(unit, exp)
-
-	return value, suffix
-}
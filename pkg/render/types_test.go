@@ -148,3 +148,29 @@ func TestNotificationViewModelJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestEventViewModelDedupKey(t *testing.T) {
+	tests := []struct {
+		event string
+		key   string
+	}{
+		{`{"Type": "alarm", "Details": [{"Name": "AlarmID", "Value": "7"}, {"Name": "AlarmPolicyID", "Value": "4085"}, {"Name": "AlarmThresholdID", "Value": "12716"}]}`, "4085.7.12716"},
+		{`{"Type": "mitigation", "Details": [{"Name": "MitigationID", "Value": "1"}, {"Name": "MitigationPolicyID", "Value": "2"}, {"Name": "MitigationMethodID", "Value": "3"}]}`, "2.1.3"},
+		{`{"Type": "custom-insight", "Details": [{"Name": "InsightID", "Value": "a430344572"}]}`, "a430344572"},
+		{`{"Type": "synthetic", "Details": [{"Name": "TestID", "Value": 12345678}, {"Name": "OriginAgentId", "Value": 266}]}`, "12345678.266"},
+		{`{"Type": "synthetic", "Details": [{"Name": "TestID", "Value": "1228"}]}`, "1228"},
+		{`{"Type": "alarm", "Details": [{"Name": "AlarmPolicyID", "Value": "4085"}, {"Name": "AlarmThresholdID", "Value": "12716"}]}`, "4085.12716"},
+		{`{"Type": "insight"}`, ""},
+		{`{"Type": "generic"}`, ""},
+	}
+
+	for _, tt := range tests {
+		var event EventViewModel
+		if err := json.Unmarshal([]byte(tt.event), &event); err != nil {
+			t.Fatalf("Failed to unmarshal EventViewModel: %v", err)
+		}
+		if key := event.DedupKey(); key != tt.key {
+			t.Errorf("Expected DedupKey %q for %s, got %q", tt.key, event.Type, key)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Units of metric values, see formatUnit.
+const (
+	Unit_BitsPerSecond    = "bits/s"
+	Unit_BytesPerSecond   = "B/s"
+	Unit_PacketsPerSecond = "pps"
+	Unit_FlowsPerSecond   = "flows/s"
+	Unit_Percent          = "%"
+	Unit_Milliseconds     = "ms"
+	Unit_Count            = "count"
+)
+
+// Unit systems of formatUnit: SI prefixes are powers of 1000, the usual ones
+// for network throughput, IEC prefixes are powers of 1024.
+const (
+	UnitSystem_SI  = "si"
+	UnitSystem_IEC = "iec"
+)
+
+// MetricUnits maps names of metric details to the unit of their values.
+var MetricUnits = map[string]string{
+	"bits":                 Unit_BitsPerSecond,
+	"bytes":                Unit_BytesPerSecond,
+	"packets":              Unit_PacketsPerSecond,
+	"fps":                  Unit_FlowsPerSecond,
+	"flows":                Unit_FlowsPerSecond,
+	"unique_src_ip":        Unit_Count,
+	"unique_dst_ip":        Unit_Count,
+	"unique_src_as":        Unit_Count,
+	"unique_dst_as":        Unit_Count,
+	"unique_src_port":      Unit_Count,
+	"unique_dst_port":      Unit_Count,
+	"unique_geo_src":       Unit_Count,
+	"unique_geo_dst":       Unit_Count,
+	"retransmits_out":      Unit_PacketsPerSecond,
+	"perc_retransmits_out": Unit_Percent,
+	"perc_out_of_order_in": Unit_Percent,
+	"perc_fragments":       Unit_Percent,
+	"client_latency":       Unit_Milliseconds,
+	"server_latency":       Unit_Milliseconds,
+	"appl_latency":         Unit_Milliseconds,
+}
+
+// prefixedUnits are the units which take metric prefixes, true for those
+// which also take IEC ones.
+var prefixedUnits = map[string]bool{
+	Unit_BitsPerSecond:    true,
+	Unit_BytesPerSecond:   true,
+	Unit_PacketsPerSecond: false,
+	Unit_FlowsPerSecond:   false,
+}
+
+// Prefixes of powers of the base, from the first one: SI ones up to quetta
+// (1000^10) and IEC ones up to yobi (1024^8).
+const (
+	siPrefixes  = "kMGTPEZYRQ"
+	iecPrefixes = "KMGTPEZY"
+)
+
+// formatValue formats the value in the unit, returning the number and the
+// unit label, possibly with a prefix, e.g. 58555.9 bits/s is 58.56 kbits/s.
+func formatValue(value float64, unit, system string) (string, string) {
+	switch unit {
+	case Unit_Milliseconds:
+		switch abs := math.Abs(value); {
+		case rounded(abs) >= 1000:
+			return formatNumber(value / 1000), "s"
+		case abs > 0 && rounded(abs*1000) < 1000:
+			return formatNumber(value * 1000), "µs"
+		}
+		return formatNumber(value), unit
+	case Unit_Count, "":
+		return formatNumber(value), ""
+	}
+
+	iec, ok := prefixedUnits[unit]
+	if !ok {
+		return formatNumber(value), unit
+	}
+	base, infix, prefixes := 1000.0, "", siPrefixes
+	if iec && system == UnitSystem_IEC {
+		base, infix, prefixes = 1024, "i", iecPrefixes
+	}
+	exp := 0
+	if abs := rounded(value); abs >= base {
+		exp = min(int(math.Log(abs)/math.Log(base)), len(prefixes))
+		// e.g. 999999 is 999.999 k, which is printed as 1 M rather than 1000.00 k
+		if exp < len(prefixes) && rounded(abs/math.Pow(base, float64(exp))) >= base {
+			exp++
+		}
+	}
+	if exp == 0 {
+		return formatNumber(value), unit
+	}
+	return formatNumber(value / math.Pow(base, float64(exp))), string(prefixes[exp-1]) + infix + unit
+}
+
+// rounded returns the absolute value rounded as printed by formatNumber.
+func rounded(value float64) float64 {
+	return math.Round(math.Abs(value)*100) / 100
+}
+
+// formatNumber prints up to 2 decimals, none when the fraction is below 0.05.
+func formatNumber(value float64) string {
+	if _, fraction := math.Modf(rounded(value)); fraction < 0.05 {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// joinUnit puts the number and the unit label together.
+func joinUnit(number, label string) string {
+	switch label {
+	case "":
+		return number
+	case Unit_Percent:
+		return number + label
+	}
+	return number + " " + label
+}
+
+// checkUnitSystem validates the optional unit system argument of formatUnit.
+func checkUnitSystem(system []string) (string, error) {
+	switch {
+	case len(system) == 0:
+		return UnitSystem_SI, nil
+	case len(system) > 1:
+		return "", fmt.Errorf("formatUnit takes at most one unit system")
+	}
+	switch res := strings.ToLower(system[0]); res {
+	case UnitSystem_SI, UnitSystem_IEC:
+		return res, nil
+	}
+	return "", fmt.Errorf("unknown unit system %q, expected si or iec", system[0])
+}
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func Test_FormatValue(t *testing.T) {
+	tests := []struct {
+		value  float64
+		unit   string
+		system string
+		number string
+		label  string
+	}{
+		{value: 58555.9140625, unit: Unit_BitsPerSecond, number: "58.56", label: "kbits/s"},
+		{value: 58555.9140625, unit: Unit_BitsPerSecond, system: UnitSystem_IEC, number: "57.18", label: "Kibits/s"},
+		{value: 2.5e9, unit: Unit_BytesPerSecond, number: "2.50", label: "GB/s"},
+		{value: 1.5e6, unit: Unit_PacketsPerSecond, system: UnitSystem_IEC, number: "1.50", label: "Mpps"},
+		{value: 999, unit: Unit_FlowsPerSecond, number: "999", label: "flows/s"},
+		{value: 0, unit: Unit_BitsPerSecond, number: "0", label: "bits/s"},
+		{value: -2500000, unit: Unit_BitsPerSecond, number: "-2.50", label: "Mbits/s"},
+		{value: 3e24, unit: Unit_BitsPerSecond, number: "3", label: "Ybits/s"},
+		{value: 1.5e30, unit: Unit_BitsPerSecond, number: "1.50", label: "Qbits/s"},
+		{value: 2e33, unit: Unit_BitsPerSecond, number: "2000", label: "Qbits/s"},
+		{value: 3 * math.Pow(1024, 8), unit: Unit_BytesPerSecond, system: UnitSystem_IEC, number: "3", label: "YiB/s"},
+		{value: 5e27, unit: Unit_BytesPerSecond, system: UnitSystem_IEC, number: "4135.90", label: "YiB/s"},
+		{value: 12.5, unit: Unit_Percent, number: "12.50", label: "%"},
+		{value: 0.25, unit: Unit_Milliseconds, number: "250", label: "µs"},
+		{value: 1500, unit: Unit_Milliseconds, number: "1.50", label: "s"},
+		{value: 1, unit: Unit_Count, number: "1", label: ""},
+		{value: 999999, unit: Unit_BitsPerSecond, number: "1", label: "Mbits/s"},
+		{value: 999.999, unit: Unit_BitsPerSecond, number: "1", label: "kbits/s"},
+		{value: -999.999, unit: Unit_BitsPerSecond, number: "-1", label: "kbits/s"},
+		{value: 1e6, unit: Unit_BitsPerSecond, number: "1", label: "Mbits/s"},
+		{value: 1023.99, unit: Unit_BytesPerSecond, system: UnitSystem_IEC, number: "1023.99", label: "B/s"},
+		{value: 1023.999, unit: Unit_BytesPerSecond, system: UnitSystem_IEC, number: "1", label: "KiB/s"},
+		{value: 1048575.99, unit: Unit_BytesPerSecond, system: UnitSystem_IEC, number: "1", label: "MiB/s"},
+		{value: 999.999, unit: Unit_Milliseconds, number: "1", label: "s"},
+		{value: 0.9999999, unit: Unit_Milliseconds, number: "1", label: "ms"},
+	}
+
+	for _, tt := range tests {
+		number, label := formatValue(tt.value, tt.unit, tt.system)
+		if number != tt.number || label != tt.label {
+			t.Errorf("Expected %v %s (%s) to be %s %s, got %s %s", tt.value, tt.unit, tt.system, tt.number, tt.label, number, label)
+		}
+	}
+}
+
+func Test_RenderFormatUnit(t *testing.T) {
+	tests := []struct {
+		template string
+		output   string
+		error    string
+	}{
+		{template: `{{ range .Event.Details.WithTag "metric" }}{{ formatUnit .Value .Unit }};{{ end }}`, output: "58.56 kbits/s;11.20 pps;1;"},
+		{template: `{{ formatUnit 12.5 "%" }} {{ formatUnit 2048 "B/s" "IEC" }}`, output: "12.50% 2 KiB/s"},
+		{template: `{{ formatUnit "fast" "bits/s" }}`, error: "don't know how to convert string to float64"},
+		{template: `{{ formatUnit 1 "bits/s" "metric" }}`, error: `unknown unit system "metric", expected si or iec`},
+	}
+
+	for _, tt := range tests {
+		resp := Render(RenderRequest{Template: tt.template, Data: TestingViewModels["alarm"]})
+		if resp.Output != tt.output || !strings.Contains(resp.Error, tt.error) || (tt.error == "") != (resp.Error == "") {
+			t.Errorf("Expected %q (error %q) for %s, got %q (error %q)", tt.output, tt.error, tt.template, resp.Output, resp.Error)
+		}
+	}
+}
+
+func Test_PrettifiedMetrics(t *testing.T) {
+	details := EventViewModelDetails{
+		{Name: "bits", Value: 0.0, Tag: DetailTag_Metric},
+		{Name: "perc_fragments", Value: 3, Tag: DetailTag_Metric},
+		{Name: "custom", Label: "Custom Metric", Value: 42.123, Tag: DetailTag_Metric},
+		{Name: "state", Value: "n/a", Tag: DetailTag_Metric},
+		{Name: "DeviceName", Value: "Ashburn DC3"},
+	}
+	expected := []string{"0 bits/s", "3 %", "42.12 Custom Metric", "n/a state"}
+
+	metrics := details.PrettifiedMetrics()
+	if len(metrics) != len(expected) {
+		t.Fatalf("Expected %d metrics, got %d", len(expected), len(metrics))
+	}
+	for i, metric := range metrics {
+		if got := fmt.Sprint(metric.Value) + " " + metric.LabelOrName(); got != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], got)
+		}
+	}
+}
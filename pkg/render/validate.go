@@ -0,0 +1,64 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kentik/custom-notification-templates/pkg/schemas"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ValidateOutput checks rendered output against the payload schema of the
+// target platform, one of schemas.Platforms(). Output which is not valid JSON
+// or does not match the schema is reported as *OutputError, located at the
+// first offending value; the message lists all problems found.
+func ValidateOutput(platform, output string) error {
+	schema := schemas.PlatformSchema(platform)
+	if schema == nil {
+		return fmt.Errorf("unknown platform %q, expected one of: %s", platform, strings.Join(schemas.Platforms(), ", "))
+	}
+	if err := validateJSON("", output); err != nil {
+		return err
+	}
+
+	result, err := schema.Validate(gojsonschema.NewStringLoader(output))
+	if err != nil {
+		return &OutputError{Err: err}
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	res := &OutputError{}
+	var problems []string
+	for _, re := range result.Errors() {
+		path := schemaErrorPath(re)
+		if res.Path == "" {
+			res.Path = path
+			if start, end, ok := locateDataPath([]byte(output), path); ok {
+				res.Range = rangeOf(output, start, end)
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s", path, re.Description()))
+	}
+	res.Err = errors.New(platform + " payload does not match schema: " + strings.Join(problems, "; "))
+	return res
+}
+
+// schemaErrorPath converts the context of a schema error, e.g. (root).blocks.0.text,
+// to a JSONPath, e.g. $.blocks[0].text.
+func schemaErrorPath(re gojsonschema.ResultError) string {
+	path := "$"
+	for i, key := range strings.Split(re.Context().String("\x00"), "\x00") {
+		if i == 0 {
+			continue
+		}
+		if key != "" && strings.Trim(key, "0123456789") == "" {
+			path += "[" + key + "]"
+		} else {
+			path = jsonPathKey(path, key)
+		}
+	}
+	return path
+}
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_ValidateOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		output   string
+		path     string
+		contains []string
+		line     int
+	}{
+		{
+			name:     "Valid PagerDuty event",
+			platform: "pagerduty",
+			output:   `{"routing_key": "key", "event_action": "resolve", "dedup_key": "1"}`,
+		},
+		{
+			name:     "PagerDuty event without routing key",
+			platform: "pagerduty",
+			output:   `{"event_action": "trigger", "payload": {"summary": "Alarm", "source": "Kentik", "severity": "error"}}`,
+			path:     "$",
+			contains: []string{"routing_key is required"},
+			line:     1,
+		},
+		{
+			name:     "PagerDuty event with unknown severity",
+			platform: "pagerduty",
+			output:   "{\"routing_key\": \"key\", \"event_action\": \"trigger\",\n\"payload\": {\"summary\": \"Alarm\", \"source\": \"Kentik\", \"severity\": \"major\"}}",
+			path:     "$.payload.severity",
+			contains: []string{"must be one of the following"},
+			line:     2,
+		},
+		{
+			name:     "Triggered PagerDuty event without payload",
+			platform: "pagerduty",
+			output:   `{"routing_key": "key", "event_action": "trigger"}`,
+			path:     "$",
+			contains: []string{"payload is required"},
+			line:     1,
+		},
+		{
+			name:     "Slack header with markdown",
+			platform: "slack",
+			output:   "{\"blocks\": [\n{\"type\": \"divider\"},\n{\"type\": \"header\", \"text\": {\"type\": \"mrkdwn\", \"text\": \"Alarm\"}}]}",
+			path:     "$.blocks[1]",
+			contains: []string{`$.blocks[1].text.type: `, `"plain_text"`},
+			line:     3,
+		},
+		{
+			name:     "Invalid JSON",
+			platform: "discord",
+			output:   `{"content": "Alarm",}`,
+			contains: []string{"invalid character"},
+			line:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOutput(tt.platform, tt.output)
+			if tt.contains == nil {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return
+			}
+
+			var outputErr *OutputError
+			if !errors.As(err, &outputErr) {
+				t.Fatalf("Expected *OutputError, got %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("Expected error containing %q, got %s", s, err)
+				}
+			}
+			if outputErr.Path != tt.path || outputErr.Range.StartLine != tt.line {
+				t.Errorf("Expected error at %s on line %d, got %s on line %d", tt.path, tt.line, outputErr.Path, outputErr.Range.StartLine)
+			}
+		})
+	}
+
+	if err := ValidateOutput("carrier-pigeon", `{}`); err == nil || !strings.Contains(err.Error(), "unknown platform") {
+		t.Errorf("Expected unknown platform error, got %v", err)
+	}
+}
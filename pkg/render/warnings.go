@@ -0,0 +1,142 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning is a non-fatal problem noticed while rendering, such as a missing
+// detail, a nil Event or an unknown payload field. Pane tells whether Range
+// locates it in the template or section named by TemplateName, or in the
+// data at DataPath.
+type Warning struct {
+	Message      string `json:"message"`
+	Pane         string `json:"pane,omitempty"`
+	TemplateName string `json:"templateName,omitempty"`
+	DataPath     string `json:"dataPath,omitempty"`
+	Range
+}
+
+type warningKey struct {
+	site    int
+	message string
+}
+
+// warn records a warning for the site, once per execution of a template.
+func (s *execState) warn(site int, format string, args ...interface{}) {
+	key := warningKey{site: site, message: fmt.Sprintf(format, args...)}
+	if s.warned[key] {
+		return
+	}
+	if s.warned == nil {
+		s.warned = make(map[warningKey]bool)
+	}
+	s.warned[key] = true
+
+	res := Warning{Message: key.message, Pane: Pane_Template}
+	if site >= 0 && site < len(s.sites) {
+		res.TemplateName = s.sites[site].name
+		res.Range = s.sites[site].rng
+	}
+	s.warnings = append(s.warnings, res)
+}
+
+// takeWarnings returns warnings recorded so far and starts collecting anew.
+func (s *execState) takeWarnings() []Warning {
+	res := s.warnings
+	s.warnings, s.warned = nil, nil
+	return res
+}
+
+// detail is bound as detailFunc and replaces detail lookups by name.
+// Missing details are fine when the result is only tested, otherwise they
+// are errors in strict mode.
+func (s *execState) detail(site int, method string, details EventViewModelDetails, names ...string) (interface{}, error) {
+	for _, name := range names {
+		if detail, ok := documentedDetails()[name]; ok && detail.Deprecated {
+			s.warn(site, "deprecated detail %s used", name)
+		}
+		if (method == "Get" || method == "GetValue") && !s.sites[site].tested && !details.Has(name) {
+			if s.strict {
+				return nil, &siteError{site: site, err: fmt.Errorf("detail %s not present", name)}
+			}
+			s.warn(site, "detail %s not present", name)
+		}
+	}
+	return callDetails(details, method, names), nil
+}
+
+// event is bound as eventFunc and replaces NotificationViewModel.Event calls.
+// In strict mode a nil Event is an error.
+func (s *execState) event(site int, vm *NotificationViewModel, text string) (*EventViewModel, error) {
+	if vm == nil {
+		return nil, nil
+	}
+	event := vm.Event()
+	if event == nil {
+		if s.strict {
+			return nil, &siteError{site: site, err: fmt.Errorf("Event is nil")}
+		}
+		s.warn(site, "Event is nil")
+	}
+	return event, nil
+}
+
+// siteError is returned by functions bound per execution. Messages of such
+// errors name the internal function, restore puts back the template text.
+type siteError struct {
+	site int
+	err  error
+}
+
+func (e *siteError) Error() string {
+	return e.err.Error()
+}
+
+func (e *siteError) Unwrap() error {
+	return e.err
+}
+
+// restore rewrites an execution error raised by a function bound at the
+// site as if it came from the original method call.
+func (site warningSite) restore(err error) error {
+	msg := err.Error()
+	for _, name := range []string{detailFunc, eventFunc} {
+		start := strings.Index(msg, "at <"+name+" ")
+		suffix := ">: error calling " + name + ": "
+		if start < 0 || !strings.Contains(msg[start:], suffix) {
+			continue
+		}
+		end := start + strings.Index(msg[start:], suffix) + len(suffix)
+		msg = msg[:start] + fmt.Sprintf("at <%s>: error calling %s: ", site.text, site.method) + msg[end:]
+		break
+	}
+	return &restoredError{msg: msg, err: err}
+}
+
+// restoredError replaces the message of an error while keeping it in the chain.
+type restoredError struct {
+	msg string
+	err error
+}
+
+func (e *restoredError) Error() string {
+	return e.msg
+}
+
+func (e *restoredError) Unwrap() error {
+	return e.err
+}
+
+// callDetails calls the named lookup method of details.
+func callDetails(details EventViewModelDetails, method string, names []string) interface{} {
+	switch method {
+	case "Get":
+		return details.Get(names[0])
+	case "GetValue":
+		return details.GetValue(names[0])
+	case "Has":
+		return details.Has(names[0])
+	}
+	return details.WithNames(names...)
+}
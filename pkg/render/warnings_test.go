@@ -0,0 +1,179 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_RenderWarnings(t *testing.T) {
+	noEvents := json.RawMessage(`{"CompanyID": 1, "CompanyName": "Test", "Events": []}`)
+
+	tests := []struct {
+		name     string
+		template string
+		data     json.RawMessage
+		output   string
+		want     []Warning
+	}{
+		{
+			name:     "Missing detail",
+			template: `{{ .Event.Details.GetValue "AlarmPolicyName" }}`,
+			data:     TestingViewModels["insight"],
+			output:   "<no value>",
+			want: []Warning{
+				{Message: "detail AlarmPolicyName not present", Pane: Pane_Template, TemplateName: "template", Range: Range{StartLine: 1, StartColumn: 18, EndLine: 1, EndColumn: 45}},
+			},
+		},
+		{
+			name:     "Deprecated detail reported once per site",
+			template: "{{ range .Events }}\n{{- if .Details.Has \"AlarmSeverityLabel\" }}yes{{ else }}no{{ end }}\n{{- end }}",
+			data:     TestingViewModels["alarm"],
+			output:   "no",
+			want: []Warning{
+				{Message: "deprecated detail AlarmSeverityLabel used", Pane: Pane_Template, TemplateName: "template", Range: Range{StartLine: 2, StartColumn: 16, EndLine: 2, EndColumn: 41}},
+			},
+		},
+		{
+			name:     "Nil event",
+			template: `{{ if .Event }}yes{{ end }}{{ toJSON $.Event }}`,
+			data:     noEvents,
+			output:   "null",
+			want: []Warning{
+				{Message: "Event is nil", Pane: Pane_Template, TemplateName: "template", Range: Range{StartLine: 1, StartColumn: 39, EndLine: 1, EndColumn: 45}},
+			},
+		},
+		{
+			name:     "Present detail",
+			template: `{{ with .Event }}{{ .Details.GetValue "AlarmSeverity" }}{{ end }}`,
+			data:     TestingViewModels["alarm"],
+			output:   "major",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Render(RenderRequest{Template: tt.template, Data: tt.data})
+			if resp.Error != "" {
+				t.Fatalf("Unexpected error: %s", resp.Error)
+			}
+			if resp.Output != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, resp.Output)
+			}
+			if len(resp.Warnings) != len(tt.want) {
+				t.Fatalf("Expected %d warnings, got %+v", len(tt.want), resp.Warnings)
+			}
+			for i := range resp.Warnings {
+				if resp.Warnings[i] != tt.want[i] {
+					t.Errorf("Expected %+v, got %+v", tt.want[i], resp.Warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_RenderWarnings_NilEventError(t *testing.T) {
+	resp := Render(RenderRequest{
+		Template: `{{ .Event.Details.GetValue "AlarmSeverity" }}`,
+		Data:     json.RawMessage(`{"CompanyID": 1, "Events": []}`),
+	})
+	if resp.Action != ".Event.Details" {
+		t.Errorf("Expected failing action .Event.Details, got %q", resp.Action)
+	}
+	if len(resp.Warnings) != 1 || resp.Warnings[0].Message != "Event is nil" {
+		t.Errorf("Expected nil event warning, got %+v", resp.Warnings)
+	}
+}
+
+func Test_RenderWarnings_UnknownFields(t *testing.T) {
+	data := json.RawMessage(`{
+  "CompanyID": 1,
+  "companyName": "Test",
+  "Region": "eu",
+  "Events": [{"Type": "alarm", "Priority": 5}]
+}`)
+	template := `{{ .CompanyName }} {{ .Extra.Region }} {{ .Event.Extra.Priority }}`
+
+	resp := Render(RenderRequest{Template: template, Data: data})
+	if resp.Error != "" {
+		t.Fatalf("Unexpected error: %s", resp.Error)
+	}
+	if resp.Output != "Test eu 5" {
+		t.Errorf("Expected unknown fields available as Extra, got %q", resp.Output)
+	}
+
+	want := []Warning{
+		{Message: "unknown field Region", Pane: Pane_Data, DataPath: "$.Region", Range: Range{StartLine: 4, StartColumn: 13, EndLine: 4, EndColumn: 17}},
+		{Message: "unknown field Priority", Pane: Pane_Data, DataPath: "$.Events[0].Priority", Range: Range{StartLine: 5, StartColumn: 44, EndLine: 5, EndColumn: 45}},
+	}
+	if len(resp.Warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %+v", len(want), resp.Warnings)
+	}
+	for i := range want {
+		if resp.Warnings[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], resp.Warnings[i])
+		}
+	}
+
+	strict := Render(RenderRequest{Template: template, Data: data, Strict: true})
+	if strict.ErrorKind != ErrorKind_Data || strict.DataPath != "$.Region" {
+		t.Errorf("Expected unknown field rejected in strict mode, got %s at %q", strict.Error, strict.DataPath)
+	}
+}
+
+func Test_RenderErrors_RewrittenActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    RenderRequest
+		action string
+	}{
+		{
+			name:   "Event chain argument",
+			req:    RenderRequest{Template: `{{ index .Event.Details 99 }}`},
+			action: "index .Event.Details 99",
+		},
+		{
+			name:   "Comparison",
+			req:    RenderRequest{Template: `{{ if eq .Event.Type 5 }}{{ end }}`},
+			action: "eq .Event.Type 5",
+		},
+		{
+			name:   "Range value",
+			req:    RenderRequest{Template: `{{ range $i, $c := .CompanyName }}{{ end }}`},
+			action: ".CompanyName",
+		},
+		{
+			name:   "JSON template",
+			req:    RenderRequest{Template: `{"x": {{ index .Event.Details 99 }}}`, JSON: true},
+			action: "index .Event.Details 99",
+		},
+		{
+			name:   "Section",
+			req:    RenderRequest{Template: `{{ define "x" }}{{ lt .Event.Type 1 }}{{ end }}`, Sections: map[string]string{"body": `{{ template "x" . }}`}},
+			action: "lt .Event.Type 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Data = TestingViewModels["alarm"]
+			resp := NewRenderer(0).Render(tt.req)
+			if resp.Sections != nil {
+				resp = resp.Sections["body"]
+			}
+			if resp.Action != tt.action {
+				t.Errorf("Expected action %q, got %q", tt.action, resp.Action)
+			}
+			if resp.Error == "" || strings.Contains(resp.Error, "_render") {
+				t.Errorf("Expected error quoting the template, got %q", resp.Error)
+			}
+		})
+	}
+}
+
+func Test_RenderErrors_InternalFuncs(t *testing.T) {
+	resp := Render(RenderRequest{Template: `{{ _render_event 0 . "x" }}`, Data: TestingViewModels["alarm"]})
+	if resp.ErrorKind != ErrorKind_Parse || !strings.Contains(resp.Error, `function "_render_event" not defined`) {
+		t.Errorf("Expected internal functions not to be callable, got %q (%s)", resp.Error, resp.ErrorKind)
+	}
+}
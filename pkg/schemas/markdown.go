@@ -40,12 +40,16 @@ func IntoMarkdown(details []Detail) string {
 		if tag == "" {
 			tag = "_(empty)_"
 		}
+		description := detail.Description
+		if detail.Deprecated {
+			description = "**Deprecated.** " + description
+		}
 
 		fmt.Fprintf(&builder, "| %s | %s | %s | %s | %s | %s |\n",
 			name,
 			tag,
 			detail.When,
-			detail.Description,
+			description,
 			jsonPrettyStringify(detail.Value),
 			htmlList(detail.Examples),
 		)
@@ -8,4 +8,8 @@ import (
 
 func TestIntoMarkdown(t *testing.T) {
 	assert.NotEmpty(t, IntoMarkdown(Details()))
+
+	md := IntoMarkdown([]Detail{{Name: "Old", Description: "Label", Deprecated: true}, {Name: "New", Description: "Label"}})
+	assert.Contains(t, md, "| Old | _(empty)_ |  | **Deprecated.** Label |")
+	assert.Contains(t, md, "| New | _(empty)_ |  | Label |")
 }
@@ -1,9 +1,13 @@
 package schemas
 
 import (
-	_ "embed"
+	"embed"
 	"encoding/json"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/xeipuuv/gojsonschema"
 	"gopkg.in/yaml.v3"
@@ -12,11 +16,15 @@ import (
 //go:embed details.yaml
 var detailsYaml []byte
 
+//go:embed platforms/*.json
+var platformsFS embed.FS
+
 type Detail struct {
 	Name        string `yaml:"Name"`
 	Tag         string `yaml:"Tag"`
 	Description string `yaml:"Description"`
 	When        string `yaml:"When"`
+	Deprecated  bool   `yaml:"Deprecated"`
 	Examples    []any  `yaml:"Examples"`
 	Value       any    `yaml:"Value"`
 }
@@ -42,3 +50,40 @@ func (ds *Detail) ValueSchema() *gojsonschema.Schema {
 	}
 	return schema
 }
+
+// Platforms returns names of the platforms with a bundled payload schema.
+func Platforms() []string {
+	var result []string
+	for name := range platformSchemas() {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// PlatformSchema returns the JSON Schema of payloads accepted by the
+// platform, or nil if there is no schema bundled for it.
+func PlatformSchema(platform string) *gojsonschema.Schema {
+	return platformSchemas()[platform]
+}
+
+var platformSchemas = sync.OnceValue(func() map[string]*gojsonschema.Schema {
+	entries, err := platformsFS.ReadDir("platforms")
+	if err != nil {
+		panic(fmt.Sprintf("Error reading platform schemas: %s", err))
+	}
+
+	result := make(map[string]*gojsonschema.Schema)
+	for _, entry := range entries {
+		data, err := platformsFS.ReadFile(path.Join("platforms", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("Error reading platform schema %s: %s", entry.Name(), err))
+		}
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+		if err != nil {
+			panic(fmt.Sprintf("Error compiling JSON Schema for platform %s: %s", entry.Name(), err))
+		}
+		result[strings.TrimSuffix(entry.Name(), ".json")] = schema
+	}
+	return result
+})
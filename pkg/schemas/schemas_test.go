@@ -56,3 +56,11 @@ func Test_ValidSchemas(t *testing.T) {
 		assert.NotNil(t, ds.ValueSchema())
 	}
 }
+
+func Test_PlatformSchemas(t *testing.T) {
+	assert.Equal(t, []string{"discord", "msteams", "opsgenie", "pagerduty", "servicenow", "slack"}, Platforms())
+	for _, platform := range Platforms() {
+		assert.NotNil(t, PlatformSchema(platform), "Schema must compile for %s", platform)
+	}
+	assert.Nil(t, PlatformSchema("carrier-pigeon"))
+}
@@ -0,0 +1,191 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateInfo describes a template, as given in the YAML front-matter at
+// the top of its leading comment:
+//
+//	{{- /*
+//	---
+//	title: Slack
+//	platform: slack
+//	---
+//	Free-form comments.
+//	*/ -}}
+type TemplateInfo struct {
+	// Name is the file name of the template
+	Name string `yaml:"-" json:"name"`
+	// Title is a human readable name of the template
+	Title string `yaml:"title" json:"title"`
+	// Platform is the target platform, one of schemas.Platforms() when the
+	// payload schema of the platform is bundled
+	Platform string `yaml:"platform" json:"platform,omitempty"`
+	// ContentType is the content type of the rendered payload
+	ContentType string `yaml:"contentType" json:"contentType"`
+	// WebhookURL is the URL (pattern) to configure in the webhook
+	WebhookURL string `yaml:"webhookURL" json:"webhookURL,omitempty"`
+	// EventTypes lists types of events handled by the template
+	EventTypes []string `yaml:"eventTypes" json:"eventTypes"`
+	// SingleEvent templates handle notifications with a single event only,
+	// rendering an empty payload for digests
+	SingleEvent bool `yaml:"singleEvent" json:"singleEvent"`
+	// FanOut templates are meant to be rendered once per event of a digest,
+	// the receiver taking a single event per request
+	FanOut bool `yaml:"fanOut" json:"fanOut,omitempty"`
+	// Params declares parameters the template expects in the render request
+	Params []ParamSpec `yaml:"params" json:"params,omitempty"`
+}
+
+// Parameter types of ParamSpec.
+const (
+	ParamType_String  = "string"
+	ParamType_Number  = "number"
+	ParamType_Integer = "integer"
+	ParamType_Boolean = "boolean"
+)
+
+// ParamSpec declares a template parameter, such as a routing key or a channel
+// name, which is passed alongside the payload (RenderRequest.Params).
+type ParamSpec struct {
+	Name string `yaml:"name" json:"name"`
+	// Type is one of ParamType_*, string when empty
+	Type        string `yaml:"type" json:"type,omitempty"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Required    bool   `yaml:"required" json:"required,omitempty"`
+	// Secret parameters, such as API keys, are masked in render previews and
+	// should not be displayed once entered
+	Secret bool `yaml:"secret" json:"secret,omitempty"`
+	// Default is used when the parameter is not passed
+	Default interface{} `yaml:"default" json:"default,omitempty"`
+}
+
+// TypeOrDefault returns the type of the parameter, string when not given.
+func (p ParamSpec) TypeOrDefault() string {
+	if p.Type == "" {
+		return ParamType_String
+	}
+	return p.Type
+}
+
+// Accepts tells whether value, decoded from JSON or YAML, is of the type of
+// the parameter. Integers are numbers, and whole numbers are integers.
+func (p ParamSpec) Accepts(value interface{}) bool {
+	typ := p.TypeOrDefault()
+	if n, ok := value.(json.Number); ok {
+		if typ == ParamType_Integer {
+			_, err := n.Int64()
+			return err == nil
+		}
+		return typ == ParamType_Number
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return typ == ParamType_String
+	case reflect.Bool:
+		return typ == ParamType_Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typ == ParamType_Number || typ == ParamType_Integer
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		return typ == ParamType_Number || typ == ParamType_Integer && f == math.Trunc(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
+
+// ErrNoFrontMatter is returned by ParseInfo for templates without front-matter.
+var ErrNoFrontMatter = errors.New("no front-matter")
+
+var frontMatterRegex = regexp.MustCompile(`(?s)^\s*\{\{-?\s*/\*\s*?\r?\n---\r?\n(.*?)\r?\n---\r?\n`)
+
+// ParseInfo reads the front-matter of the named template, which may be
+// a built-in template as well as a user one. Front-matter of built-in
+// templates is parsed once, as long as their text is left as it is.
+func ParseInfo(name string, text []byte) (TemplateInfo, error) {
+	if builtin, err := Asset(name); err == nil && bytes.Equal(builtin, text) {
+		if all, err := infos(); err == nil {
+			return all[name], nil
+		}
+	}
+	return parseInfo(name, text)
+}
+
+func parseInfo(name string, text []byte) (TemplateInfo, error) {
+	info := TemplateInfo{Name: name}
+	matches := frontMatterRegex.FindSubmatch(text)
+	if matches == nil {
+		return info, fmt.Errorf("template %s has %w", name, ErrNoFrontMatter)
+	}
+	if err := yaml.Unmarshal(matches[1], &info); err != nil {
+		return info, fmt.Errorf("template %s has invalid front-matter: %w", name, err)
+	}
+	for _, param := range info.Params {
+		switch {
+		case param.Name == "":
+			return info, fmt.Errorf("template %s has a param without a name", name)
+		case param.Type != "" && param.Type != ParamType_String && param.Type != ParamType_Number &&
+			param.Type != ParamType_Integer && param.Type != ParamType_Boolean:
+			return info, fmt.Errorf("template %s has param %s of unknown type %s", name, param.Name, param.Type)
+		case param.Default != nil && !param.Accepts(param.Default):
+			return info, fmt.Errorf("template %s has param %s with a default which is not of type %s", name, param.Name, param.TypeOrDefault())
+		}
+	}
+	return info, nil
+}
+
+var infos = sync.OnceValues(func() (map[string]TemplateInfo, error) {
+	result := make(map[string]TemplateInfo)
+	for _, name := range AssetNames() {
+		text, err := Asset(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read template %s: %w", name, err)
+		}
+		info, err := parseInfo(name, text)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = info
+	}
+	return result, nil
+})
+
+// List returns information about all templates, ordered by name.
+func List() ([]TemplateInfo, error) {
+	all, err := infos()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]TemplateInfo, 0, len(all))
+	for _, info := range all {
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Get returns information about the named template.
+func Get(name string) (TemplateInfo, error) {
+	all, err := infos()
+	if err != nil {
+		return TemplateInfo{}, err
+	}
+	info, ok := all[name]
+	if !ok {
+		return TemplateInfo{}, fmt.Errorf("template %s not found", name)
+	}
+	return info, nil
+}
@@ -0,0 +1,75 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_List(t *testing.T) {
+	infos, err := List()
+	require.NoError(t, err)
+	require.Len(t, infos, len(AssetNames()))
+	for _, info := range infos {
+		assert.NotEmpty(t, info.Title, "Title must not be empty for %s", info.Name)
+		assert.NotEmpty(t, info.ContentType, "ContentType must not be empty for %s", info.Name)
+		assert.NotEmpty(t, info.EventTypes, "EventTypes must not be empty for %s", info.Name)
+	}
+}
+
+func Test_Get(t *testing.T) {
+	info, err := Get("pagerduty.json.tmpl")
+	require.NoError(t, err)
+	assert.Equal(t, "pagerduty", info.Platform)
+	assert.Equal(t, "https://events.pagerduty.com/v2/enqueue", info.WebhookURL)
+	assert.False(t, info.SingleEvent)
+	assert.True(t, info.FanOut)
+
+	_, err = Get("carrier-pigeon.json.tmpl")
+	assert.Error(t, err)
+}
+
+func Test_ParseInfo_BuiltIn(t *testing.T) {
+	text, err := Asset("pagerduty.json.tmpl")
+	require.NoError(t, err)
+	cached, err := ParseInfo("pagerduty.json.tmpl", text)
+	require.NoError(t, err)
+	parsed, err := parseInfo("pagerduty.json.tmpl", text)
+	require.NoError(t, err)
+	assert.Equal(t, parsed, cached)
+
+	info, err := ParseInfo("pagerduty.json.tmpl", append(text[:len(text):len(text)], "{{/* edited */}}"...))
+	require.NoError(t, err)
+	assert.Equal(t, parsed, info)
+	_, err = ParseInfo("pagerduty.json.tmpl", []byte("{{/* Notes */}}{}"))
+	assert.ErrorIs(t, err, ErrNoFrontMatter)
+}
+
+func Test_ParseInfo(t *testing.T) {
+	info, err := ParseInfo("test.tmpl", []byte("{{/*\n---\ntitle: Test\nsingleEvent: true\nparams:\n  - name: channel\n    required: true\n  - name: retries\n    type: integer\n    default: 3\n---\nNotes\n*/}}{}"))
+	require.NoError(t, err)
+	assert.Equal(t, TemplateInfo{Name: "test.tmpl", Title: "Test", SingleEvent: true, Params: []ParamSpec{
+		{Name: "channel", Required: true},
+		{Name: "retries", Type: ParamType_Integer, Default: 3},
+	}}, info)
+
+	info, err = ParseInfo("test.tmpl", []byte("{{- /*\r\n---\r\ntitle: Test\r\nplatform: slack\r\n---\r\nNotes\r\n*/ -}}\r\n{}"))
+	require.NoError(t, err)
+	assert.Equal(t, TemplateInfo{Name: "test.tmpl", Title: "Test", Platform: "slack"}, info)
+
+	_, err = ParseInfo("test.tmpl", []byte("{{/* Notes */}}{}"))
+	assert.ErrorIs(t, err, ErrNoFrontMatter)
+
+	_, err = ParseInfo("test.tmpl", []byte("{{/*\n---\ntitle: [\n---\n*/}}{}"))
+	assert.ErrorContains(t, err, "invalid front-matter")
+
+	_, err = ParseInfo("test.tmpl", []byte("{{/*\n---\nparams:\n  - name: channel\n    type: channel\n---\n*/}}{}"))
+	assert.ErrorContains(t, err, "unknown type channel")
+
+	_, err = ParseInfo("test.tmpl", []byte("{{/*\n---\nparams:\n  - name: retries\n    type: integer\n    default: three\n---\n*/}}{}"))
+	assert.ErrorContains(t, err, "param retries with a default which is not of type integer")
+
+	_, err = ParseInfo("test.tmpl", []byte("{{/*\n---\nparams:\n  - name: channel\n    default: 3\n---\n*/}}{}"))
+	assert.ErrorContains(t, err, "param channel with a default which is not of type string")
+}
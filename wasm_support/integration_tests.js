@@ -22,6 +22,28 @@ function test(name, fn) {
     throw err;
   }
 }
+async function testAsync(name, fn) {
+  testsRun++;
+  try {
+    await fn();
+    testsPassed++;
+    console.log(`  PASS: ${name}`);
+  } catch (err) {
+    console.error(`  FAIL: ${name}`);
+    console.error(`        ${err.message}`);
+    throw err;
+  }
+}
+
+// slowTemplate renders 2^depth nested template calls
+function slowTemplate(depth) {
+  let text = '';
+  for (let i = 0; i < depth; i++) {
+    text += `{{ define "t${i}" }}{{ template "t${i + 1}" . }}{{ template "t${i + 1}" . }}{{ end }}`;
+  }
+  return text + `{{ define "t${depth}" }}{{ range .Events }}{{ end }}{{ end }}{{ template "t0" . }}`;
+}
+
 async function runWasm() {
   const go = new Go();
 
@@ -51,6 +73,26 @@ async function runWasm() {
     assert.ok(result.line === 3 || result.line > 0, 'Should have line number');
   });
 
+  test('Error range covers the whole action', () => {
+    const result = JSON.parse(global.goTemplateRender('{{ .CompanyName }} {{ .Event.Foo }}', data));
+    assert.ok(result.error, 'Should have error');
+    assert.strictEqual(result.startColumn, 20, 'Range should start at the action');
+    assert.strictEqual(result.endColumn, 36, 'Range should end after the action');
+  });
+
+  test('Skipped render is reported without error', () => {
+    const result = JSON.parse(global.goTemplateRender('{{ skip "not today" }}', data));
+    assert.ok(!result.error, 'Should not have error');
+    assert.strictEqual(result.skipped, true, 'Should be skipped');
+    assert.strictEqual(result.skipReason, 'not today', 'Should have skip reason');
+  });
+
+  test('JSON option escapes the output', () => {
+    const result = JSON.parse(global.goTemplateRender('{"name": "{{ .CompanyName }}"}', JSON.stringify({ CompanyName: 'Say "hi"' }), { json: true }));
+    assert.strictEqual(result.error, undefined, 'Should not have errors');
+    assert.strictEqual(result.output, '{"name": "Say \\"hi\\""}', 'Quotes should be escaped');
+  });
+
   test('goTemplateGetSchema function is available', () => {
     assert.strictEqual(typeof global.goTemplateGetSchema, 'function', 'goTemplateGetSchema should be a function');
   });
@@ -63,6 +105,63 @@ async function runWasm() {
     assert.ok(schema.enums, 'Schema should have enums object');
   });
 
+  test('Built-in templates can be listed and fetched', () => {
+    const list = JSON.parse(global.goTemplateList());
+    assert.ok(list.some((t) => t.name === 'json-clean.json.tmpl'), 'List should include json-clean.json.tmpl');
+    const tmpl = JSON.parse(global.goTemplateGet('json-clean.json.tmpl'));
+    assert.strictEqual(tmpl.source, template, 'Source should match the template file');
+  });
+
+  test('Fixtures can be listed, fetched and rendered', () => {
+    const names = JSON.parse(global.goFixtureList());
+    assert.ok(names.includes('alarm'), 'Fixtures should include alarm');
+    const fixture = JSON.parse(global.goFixtureGet('alarm'));
+    assert.strictEqual(fixture.data.CompanyName, JSON.parse(data).CompanyName, 'Fixture should match alarm.json');
+    const result = JSON.parse(global.goTemplateRenderFixture('{{ .CompanyName }}', 'alarm'));
+    assert.strictEqual(result.output, fixture.data.CompanyName, 'Output should match company name');
+  });
+
+  await testAsync('Async render resolves with the result', async () => {
+    const result = JSON.parse(await global.goTemplateRenderAsync('{{ .CompanyName }}', data));
+    assert.strictEqual(result.output, JSON.parse(data).CompanyName, 'Output should match company name');
+  });
+
+  await testAsync('Async render passes params', async () => {
+    const result = JSON.parse(await global.goTemplateRenderAsync('{{ param "channel" "none" }}', data, { params: { channel: '#alerts' } }));
+    assert.strictEqual(result.output, '#alerts', 'Output should be the param');
+  });
+
+  await testAsync('Async render overrides the palette', async () => {
+    const result = JSON.parse(await global.goTemplateRenderAsync('{{ importanceColor "discord" 7 }}', data, { palette: { critical: { color: '#FF0000' } } }));
+    assert.strictEqual(result.output, '16711680', 'Output should use the overridden color');
+  });
+
+  await testAsync('Async render escapes JSON output', async () => {
+    const result = JSON.parse(await global.goTemplateRenderAsync('{"name": {{ .CompanyName }}}', data, { json: true }));
+    assert.strictEqual(JSON.parse(result.output).name, JSON.parse(data).CompanyName, 'Output should be valid JSON');
+  });
+
+  await testAsync('Async render stops at the timeout', async () => {
+    const result = JSON.parse(await global.goTemplateRenderAsync(slowTemplate(30), data, { timeout: 20 }));
+    assert.strictEqual(result.errorKind, 'limit', 'Should stop with a limit error');
+    assert.ok(result.error.includes('deadline exceeded'), 'Error should mention the deadline');
+  });
+
+  await testAsync('Aborted async render rejects', async () => {
+    const controller = new AbortController();
+    controller.abort();
+    await assert.rejects(global.goTemplateRenderAsync('{{ .CompanyName }}', data, { signal: controller.signal }), { name: 'AbortError' });
+  });
+
+  await testAsync('Running async render can be aborted', async () => {
+    const controller = new AbortController();
+    const started = Date.now();
+    const pending = global.goTemplateRenderAsync(slowTemplate(30), data, { timeout: 60000, signal: controller.signal });
+    setTimeout(() => controller.abort(), 20);
+    await assert.rejects(pending, { name: 'AbortError' });
+    assert.ok(Date.now() - started < 5000, 'Render should stop soon after abort');
+  });
+
   console.log('\n======================');
   console.log(`Tests: ${testsPassed}/${testsRun} passed`);
 